@@ -1,25 +1,218 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/gabizou/middleware-generator/pkg/errors"
 	"github.com/gabizou/middleware-generator/pkg/generator"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+	_ "github.com/gabizou/middleware-generator/pkg/plugins/logging"
+	_ "github.com/gabizou/middleware-generator/pkg/plugins/metrics"
+	_ "github.com/gabizou/middleware-generator/pkg/plugins/otel"
 	_ "github.com/gabizou/middleware-generator/pkg/plugins/tracing"
 )
 
-const (
-	argsLengthRequirement = 4
+// pluginFlags collects each -plugin given, as the flag may be repeated.
+type pluginFlags []string
+
+func (p *pluginFlags) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *pluginFlags) Set(plugin string) error {
+	*p = append(*p, plugin)
+	return nil
+}
+
+var (
+	typeNames   = flag.String("type", "", "comma-separated interfaces or globs such as *Repository to generate middleware for, optionally qualified by their import path as in io.ReadWriter")
+	middlewares = flag.String("middleware", "", "comma-separated middleware func types paired with each -type, declared when missing (default the func(T) T type declared, otherwise <type>Middleware)")
+	output      = flag.String("output", "", "path of the generated file relative to the package directory (default <plugin>_<type>.go), only for a single -type and either a single -plugin or -combine; or the directory to generate into when ending in a separator or existing")
+	config      = flag.String("config", "", "YAML or JSON file describing every middleware to generate, instead of -type and the flags describing it")
+	stdout      = flag.Bool("stdout", false, "write nothing, but print the generated files to stdout, each preceded by a comment naming its path")
+	verbose     = flag.Bool("v", false, "trace the derivation of the types to stderr")
+	check       = flag.Bool("check", false, "write nothing, but print a diff and exit with 1 when the generated files are stale")
+	combine     = flag.Bool("combine", false, "generate the middleware of every -plugin into a single middleware_<type>.go")
+	packageDir  = flag.String("package-dir", "", "directory of the package declaring the types, or that the packages are relative to (default the directory of $GOFILE when run by go generate, otherwise the current directory)")
+	header      = flag.String("header", "", "additional comment for the header of the generated files, such as a copyright notice")
+	plugins     pluginFlags
 )
 
+func usage() {
+	_, _ = fmt.Fprintf(os.Stderr, "Usage of middleware-generator:\n")
+	_, _ = fmt.Fprintf(os.Stderr, "\tmiddleware-generator -type T [-middleware M] -plugin name[:key=value,...] [flags]\n")
+	_, _ = fmt.Fprintf(os.Stderr, "\tmiddleware-generator -config middleware.yaml [flags]\n")
+	_, _ = fmt.Fprintf(os.Stderr, "\tmiddleware-generator [flags] packages, such as ./..., generating for every interface annotated by //middleware:generate plugin,...\n")
+	_, _ = fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+	_, _ = fmt.Fprintf(os.Stderr, "Plugins:\n")
+	for _, name := range generator.Customizers() {
+		_, _ = fmt.Fprintf(os.Stderr, "\t%s\n", name)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\tor <name> for a middleware-gen-<name> executable on the PATH, see package plugin\n")
+}
+
 func main() {
-	if len(os.Args) != argsLengthRequirement {
-		panic(fmt.Errorf("expected exactly one argument: <source type>"))
+	flag.Var(&plugins, "plugin", "plugin to generate the middleware with, as name[:key=value,...]; may be repeated")
+	flag.BoolVar(verbose, "debug", false, "alias for -v")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *verbose {
+		interpreter.SetDebugOutput(os.Stderr)
+	}
+	var files []generator.GeneratedFile
+	if *config != "" {
+		files = generateConfig()
+	} else {
+		files = generate()
 	}
-	dir, err := os.Getwd()
+	switch {
+	case *check:
+		checkGenerated(files)
+	case *stdout:
+		for _, file := range files {
+			_, err := fmt.Fprintf(os.Stdout, "// %s\n%s", file.Path, file.Content)
+			failErr(err)
+		}
+	default:
+		for _, file := range files {
+			failErr(file.Write())
+		}
+	}
+}
+
+// generate renders the middleware described by the flags, or that of the
+// interfaces annotated in the packages given as arguments.
+func generate() []generator.GeneratedFile {
+	opts := generator.Options{
+		Patterns: flag.Args(),
+		Dir:      *packageDir,
+		Plugins:  plugins,
+		Combine:  *combine,
+		Output:   *output,
+		Header:   *header,
+		Args:     quoteArgs(generatingArgs(os.Args[1:])),
+	}
+	if *typeNames != "" {
+		opts.Types = strings.Split(*typeNames, ",")
+	}
+	if *middlewares != "" {
+		opts.Middlewares = strings.Split(*middlewares, ",")
+	}
+	if opts.Dir == "" {
+		opts.Dir, opts.PackageName = sourceDir()
+	}
+	files, err := generator.Generate(context.Background(), opts)
+	if invalid, ok := err.(errors.InvalidOptionsErr); ok {
+		failUsage(invalid.Error())
+	}
+	failErr(err)
+	return files
+}
+
+// generateConfig renders the middleware described by the -config file,
+// which the flags describing a middleware can't be combined with.
+func generateConfig() []generator.GeneratedFile {
+	if flag.NArg() > 0 {
+		failUsage("packages cannot be combined with -config")
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "type", "middleware", "plugin", "output", "combine", "package-dir", "header":
+			failUsage(fmt.Sprintf("-%s cannot be combined with -config", f.Name))
+		}
+	})
+	cfg, err := generator.LoadConfig(*config)
+	failErr(err)
+	cfg.Args = quoteArgs(generatingArgs(os.Args[1:]))
+	files, err := generator.GenerateConfig(context.Background(), cfg)
+	failErr(err)
+	return files
+}
+
+// checkGenerated prints the diff of every stale generated file, failing
+// when there is any.
+func checkGenerated(files []generator.GeneratedFile) {
+	stale := false
+	for _, file := range files {
+		diff, err := file.Diff()
+		failErr(err)
+		if diff != "" {
+			stale = true
+			fmt.Print(diff)
+		}
+	}
+	if stale {
+		_, _ = fmt.Fprintln(os.Stderr, "middleware-generator: generated files are stale, run go generate")
+		os.Exit(1)
+	}
+}
+
+// sourceDir is the directory of the package to generate for along with its
+// expected name. When run by go generate, that is the working directory
+// holding $GOFILE, whose package is $GOPACKAGE.
+func sourceDir() (string, string) {
+	wd, err := os.Getwd()
+	failErr(err)
+	goFile := os.Getenv("GOFILE")
+	if goFile == "" {
+		return wd, ""
+	}
+	if _, err := os.Stat(filepath.Join(wd, goFile)); err != nil {
+		return wd, ""
+	}
+	return wd, os.Getenv("GOPACKAGE")
+}
+
+// generatingArgs drops the flags that don't affect the generated files, such
+// as -check, so that checking records the same command in the header as
+// generating does.
+func generatingArgs(args []string) []string {
+	var generating []string
+	for _, arg := range args {
+		switch strings.TrimLeft(arg, "-") {
+		case "check", "check=true", "check=false", "stdout", "stdout=true", "stdout=false",
+			"v", "v=true", "v=false", "debug", "debug=true", "debug=false":
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+		}
+		generating = append(generating, arg)
+	}
+	return generating
+}
+
+// quoteArgs quotes the arguments that wouldn't survive being split on
+// whitespace, so the header of a generated file shows a usable command.
+func quoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return quoted
+}
+
+// failErr reports the error, every problem of which is on a line of its
+// own prefixed by its position as the compiler does, and exits.
+func failErr(err error) {
 	if err != nil {
-		panic(err)
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	g := generator.Interpret(dir, os.Args)
-	g.Print()
+}
+
+func failUsage(msg string) {
+	_, _ = fmt.Fprintf(os.Stderr, "middleware-generator: %s\n", msg)
+	flag.Usage()
+	os.Exit(2)
 }
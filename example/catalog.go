@@ -0,0 +1,22 @@
+package example
+
+import (
+	"context"
+
+	"example/domain"
+)
+
+//go:generate go run github.com/gabizou/middleware-generator/cmd/generator -type Catalog -middleware CatalogMiddleware -plugin tracer
+
+type Page[T any] struct {
+	Items []T
+	Next  string
+}
+
+type Catalog[T any] interface {
+	Get(ctx context.Context, id string) (T, error)
+	List(ctx context.Context, cursor string) (Page[T], error)
+	Foos(ctx context.Context) Page[domain.Foo]
+}
+
+type CatalogMiddleware[T any] func(Catalog[T]) Catalog[T]
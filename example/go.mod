@@ -2,4 +2,19 @@ module example
 
 go 1.18
 
-require github.com/openzipkin/zipkin-go v0.4.0
+require (
+	github.com/gabizou/middleware-generator v0.0.0-00010101000000-000000000000
+	github.com/openzipkin/zipkin-go v0.4.0
+)
+
+require (
+	github.com/dave/jennifer v1.5.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.6.0-dev.0.20220106191415-9b9b3d81d5e3 // indirect
+	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
+	golang.org/x/tools v0.1.10 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/gabizou/middleware-generator => ../
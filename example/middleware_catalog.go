@@ -0,0 +1,19 @@
+// Code generated by middleware-generator; DO NOT EDIT.
+
+package example
+
+// ChainCatalog composes the middlewares into one, the first of them being the outermost
+// wrapper, which is called first.
+func ChainCatalog[T any](mws ...CatalogMiddleware[T]) CatalogMiddleware[T] {
+	return func(svc Catalog[T]) Catalog[T] {
+		for i := len(mws) - 1; i >= 0; i-- {
+			svc = mws[i](svc)
+		}
+		return svc
+	}
+}
+
+// WrapCatalog wraps the service with the middlewares, see ChainCatalog.
+func WrapCatalog[T any](svc Catalog[T], mws ...CatalogMiddleware[T]) Catalog[T] {
+	return ChainCatalog[T](mws...)(svc)
+}
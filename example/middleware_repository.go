@@ -0,0 +1,19 @@
+// Code generated by middleware-generator; DO NOT EDIT.
+
+package example
+
+// ChainRepository composes the middlewares into one, the first of them being the outermost
+// wrapper, which is called first.
+func ChainRepository(mws ...RepoMiddleware) RepoMiddleware {
+	return func(svc Repository) Repository {
+		for i := len(mws) - 1; i >= 0; i-- {
+			svc = mws[i](svc)
+		}
+		return svc
+	}
+}
+
+// WrapRepository wraps the service with the middlewares, see ChainRepository.
+func WrapRepository(svc Repository, mws ...RepoMiddleware) Repository {
+	return ChainRepository(mws...)(svc)
+}
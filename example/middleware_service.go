@@ -0,0 +1,19 @@
+// Code generated by middleware-generator; DO NOT EDIT.
+
+package example
+
+// ChainService composes the middlewares into one, the first of them being the outermost
+// wrapper, which is called first.
+func ChainService(mws ...SvcMiddleware) SvcMiddleware {
+	return func(svc Service) Service {
+		for i := len(mws) - 1; i >= 0; i-- {
+			svc = mws[i](svc)
+		}
+		return svc
+	}
+}
+
+// WrapService wraps the service with the middlewares, see ChainService.
+func WrapService(svc Service, mws ...SvcMiddleware) Service {
+	return ChainService(mws...)(svc)
+}
@@ -2,11 +2,12 @@ package example
 
 import (
 	"context"
+	"io"
 
 	"example/domain"
 )
 
-//go:generate cd .. && go run ./cmd/generator Service SvcMiddleware tracer
+//go:generate go run github.com/gabizou/middleware-generator/cmd/generator -type Service -middleware SvcMiddleware -plugin tracer
 
 type Service interface {
 	Foo(ctx context.Context, bar string) domain.Foo
@@ -19,9 +20,10 @@ type Pill struct {
 
 type unexported []map[string]*[]*interface{}
 
-//go:generate cd .. && go run ./cmd/generator Repository RepoMiddleware tracer
+//go:generate go run github.com/gabizou/middleware-generator/cmd/generator -type Repository -middleware RepoMiddleware -plugin tracer
 
 type Repository interface {
+	io.Closer
 	Find(ctx context.Context, id string) (*domain.Foo, error)
 	Foo(ctx context.Context) (anInt int, aBool bool, aSlice []*domain.Foo, complexSlice []*[]interface{}, aMap map[string]*interface{})
 	Bar(ctx context.Context, astruct struct{ name string }) **interface {
@@ -0,0 +1,7 @@
+//go:build tools
+
+package example
+
+import (
+	_ "github.com/gabizou/middleware-generator/cmd/generator"
+)
@@ -0,0 +1,59 @@
+// Code generated by "middleware-generator -type Catalog -middleware CatalogMiddleware -plugin tracer"; DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	domain "example/domain"
+	zipkingo "github.com/openzipkin/zipkin-go"
+)
+
+func NewCatalogTracer[T any](tracer zipkingo.Tracer) CatalogMiddleware[T] {
+	return func(c Catalog[T]) Catalog[T] {
+		return &tracerCatalog[T]{
+			c:  c,
+			tr: tracer,
+		}
+	}
+}
+
+type tracerCatalog[T any] struct {
+	tr zipkingo.Tracer
+	c  Catalog[T]
+}
+
+func (t *tracerCatalog[T]) Foos(ctx context.Context) Page[domain.Foo] {
+	span, ctx := t.tr.StartSpanFromContext(ctx, "Foos")
+
+	defer func() {
+		span.Finish()
+	}()
+
+	return t.c.Foos(ctx)
+}
+func (t *tracerCatalog[T]) Get(ctx context.Context, id string) (T, error) {
+	span, ctx := t.tr.StartSpanFromContext(ctx, "Get")
+
+	defer func() {
+		span.Finish()
+	}()
+
+	r0, err := t.c.Get(ctx, id)
+	if err != nil {
+		span.Tag("error", err.Error())
+	}
+	return r0, err
+}
+func (t *tracerCatalog[T]) List(ctx context.Context, cursor string) (Page[T], error) {
+	span, ctx := t.tr.StartSpanFromContext(ctx, "List")
+
+	defer func() {
+		span.Finish()
+	}()
+
+	r0, err := t.c.List(ctx, cursor)
+	if err != nil {
+		span.Tag("error", err.Error())
+	}
+	return r0, err
+}
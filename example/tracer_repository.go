@@ -1,29 +1,28 @@
-// Code generated by "logger-middleware Repository RepoMiddleware tracer"; DO NOT EDIT.
+// Code generated by "middleware-generator -type Repository -middleware RepoMiddleware -plugin tracer"; DO NOT EDIT.
 
 package example
 
 import (
 	"context"
 	domain "example/domain"
-
 	zipkingo "github.com/openzipkin/zipkin-go"
 )
 
 func NewRepositoryTracer(tracer zipkingo.Tracer) RepoMiddleware {
 	return func(r Repository) Repository {
-		return &tracerR{
+		return &tracerRepository{
 			r:  r,
 			tr: tracer,
 		}
 	}
 }
 
-type tracerR struct {
+type tracerRepository struct {
 	tr zipkingo.Tracer
 	r  Repository
 }
 
-func (t *tracerR) Bar(ctx context.Context, astruct struct {
+func (t *tracerRepository) Bar(ctx context.Context, astruct struct {
 	name string
 }) **interface {
 	aFunc(inner func(ctx context.Context, uint2 uint) (string, error, unexported))
@@ -36,7 +35,7 @@ func (t *tracerR) Bar(ctx context.Context, astruct struct {
 
 	return t.r.Bar(ctx, astruct)
 }
-func (t *tracerR) Baz(ctx context.Context) func(ctx context.Context) error {
+func (t *tracerRepository) Baz(ctx context.Context) func(ctx context.Context) error {
 	span, ctx := t.tr.StartSpanFromContext(ctx, "Baz")
 
 	defer func() {
@@ -45,16 +44,23 @@ func (t *tracerR) Baz(ctx context.Context) func(ctx context.Context) error {
 
 	return t.r.Baz(ctx)
 }
-func (t *tracerR) Find(ctx context.Context, id string) (*domain.Foo, error) {
+func (t *tracerRepository) Close() error {
+	return t.r.Close()
+}
+func (t *tracerRepository) Find(ctx context.Context, id string) (*domain.Foo, error) {
 	span, ctx := t.tr.StartSpanFromContext(ctx, "Find")
 
 	defer func() {
 		span.Finish()
 	}()
 
-	return t.r.Find(ctx, id)
+	r0, err := t.r.Find(ctx, id)
+	if err != nil {
+		span.Tag("error", err.Error())
+	}
+	return r0, err
 }
-func (t *tracerR) Foo(ctx context.Context) (int, bool, []*domain.Foo, []*[]interface{}, map[string]*interface{}) {
+func (t *tracerRepository) Foo(ctx context.Context) (int, bool, []*domain.Foo, []*[]interface{}, map[string]*interface{}) {
 	span, ctx := t.tr.StartSpanFromContext(ctx, "Foo")
 
 	defer func() {
@@ -4,7 +4,9 @@ go 1.18
 
 require (
 	github.com/dave/jennifer v1.5.0
+	github.com/pmezard/go-difflib v1.0.0
 	golang.org/x/tools v0.1.10
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -1,8 +1,11 @@
 package errors
 
 import (
+	goerrors "errors"
 	"fmt"
+	"go/token"
 	"go/types"
+	"strings"
 )
 
 // BadMethodSignatureTypeErr represents a type assertion failure that we want to know
@@ -10,24 +13,339 @@ import (
 // an unknown/unhandled function for us to generate a wrapper around.
 type BadMethodSignatureTypeErr struct {
 	Func *types.Func
+	Pos  token.Position
 }
 
 func (b BadMethodSignatureTypeErr) Error() string {
-	return fmt.Sprintf("method by %s has %T instead of *types.Signature, violation of types.Func", b.Func.Name(), b.Func.Type())
+	return at(b.Pos, fmt.Sprintf("method %s has %T instead of *types.Signature, violation of types.Func", b.Func.Name(), b.Func.Type()))
 }
 
+// UndeclaredTypeErr represents a name that was expected to be a type, but
+// is declared as a variable, constant or function instead.
 type UndeclaredTypeErr struct {
 	Obj types.Object
+	Pos token.Position
 }
 
 func (u UndeclaredTypeErr) Error() string {
-	return fmt.Sprintf("%v is not a named type", u.Obj)
+	return at(u.Pos, fmt.Sprintf("%s is not a type (it is a %s)", u.Obj.Name(), describeObject(u.Obj)))
 }
 
+// UnexportedTypeErr represents a type declared in another package that cannot
+// be referred to by the generated code.
+type UnexportedTypeErr struct {
+	Obj types.Object
+	Pos token.Position
+}
+
+func (u UnexportedTypeErr) Error() string {
+	return at(u.Pos, fmt.Sprintf("%s is not exported by package %s", u.Obj.Name(), u.Obj.Pkg().Path()))
+}
+
+// BadMiddlewareTypeErr represents a declared middleware type that isn't a
+// function taking and returning the service, for which no factory method
+// can be generated.
+type BadMiddlewareTypeErr struct {
+	Obj     types.Object
+	Service types.Object
+	Pos     token.Position
+}
+
+func (b BadMiddlewareTypeErr) Error() string {
+	return at(b.Pos, fmt.Sprintf("%s must be declared as func(%[2]s) %[2]s (it is %s)",
+		b.Obj.Name(), b.Service.Name(), types.TypeString(b.Obj.Type().Underlying(), types.RelativeTo(b.Obj.Pkg()))))
+}
+
+// NotAnInterfaceErr represents a type to generate middleware for which is
+// not an interface.
 type NotAnInterfaceErr struct {
 	Obj types.Object
+	Pos token.Position
 }
 
 func (n NotAnInterfaceErr) Error() string {
-	return fmt.Sprintf("type %v is not an interface!", n.Obj)
+	return at(n.Pos, fmt.Sprintf("%s is not an interface (it is a %s)", n.Obj.Name(), describeType(n.Obj.Type().Underlying())))
+}
+
+// UnexportedMethodErr represents a method in the method set of an interface that
+// is unexported and declared in another package, usually by way of an embedded
+// interface, which no generated wrapper is able to implement.
+type UnexportedMethodErr struct {
+	Func *types.Func
+	Pos  token.Position
+}
+
+func (u UnexportedMethodErr) Error() string {
+	return at(u.Pos, fmt.Sprintf("method %s is unexported from package %s and cannot be implemented", u.Func.Name(), u.Func.Pkg().Path()))
+}
+
+// List aggregates the problems found at once, such as with several types.
+type List []error
+
+func (l List) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (l List) Unwrap() []error {
+	return l
+}
+
+// As finds the first problem matching the target, as errors.As does,
+// which only unwraps the problems by Unwrap as of Go 1.20.
+func (l List) As(target interface{}) bool {
+	for _, err := range l {
+		if goerrors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether any of the problems matches the target, as errors.Is
+// does, which only unwraps the problems by Unwrap as of Go 1.20.
+func (l List) Is(target error) bool {
+	for _, err := range l {
+		if goerrors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Err is nil when there are no problems, the only problem when there is a
+// single one, and the List otherwise.
+func (l List) Err() error {
+	switch len(l) {
+	case 0:
+		return nil
+	case 1:
+		return l[0]
+	}
+	return l
+}
+
+// at prefixes the message with the position, when known, as the compiler does.
+func at(pos token.Position, msg string) string {
+	if !pos.IsValid() {
+		return msg
+	}
+	return pos.String() + ": " + msg
+}
+
+func describeObject(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Var:
+		return "variable"
+	case *types.Const:
+		return "constant"
+	case *types.Func:
+		return "function"
+	case *types.PkgName:
+		return "package"
+	}
+	return fmt.Sprintf("%T", obj)
+}
+
+func describeType(typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Signature:
+		return "func"
+	case *types.Map:
+		return "map"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Chan:
+		return "chan"
+	case *types.Basic:
+		return typ.Name()
+	}
+	return typ.String()
+}
+
+// PackageLoadErr represents a package that could not be loaded from the
+// directory by its pattern.
+type PackageLoadErr struct {
+	Dir     string
+	Pattern string
+	Err     error
+}
+
+func (p PackageLoadErr) Error() string {
+	return fmt.Sprintf("loading %s from %s: %v", p.Pattern, p.Dir, p.Err)
+}
+
+func (p PackageLoadErr) Unwrap() error {
+	return p.Err
+}
+
+// NoPackageErr represents a directory without any Go files to generate into.
+type NoPackageErr struct {
+	Dir string
+}
+
+func (n NoPackageErr) Error() string {
+	return fmt.Sprintf("no package found to generate into in %s", n.Dir)
+}
+
+// PackageNameErr represents a package that isn't the one the generator was
+// told to expect, such as by $GOPACKAGE when run by go generate.
+type PackageNameErr struct {
+	Dir      string
+	Expected string
+	Found    string
+}
+
+func (p PackageNameErr) Error() string {
+	return fmt.Sprintf("expected package %s in %s, found %s", p.Expected, p.Dir, p.Found)
+}
+
+// TypeNotFoundErr represents a type that isn't declared by its package,
+// along with the name of a similar type it may have been mistaken for.
+type TypeNotFoundErr struct {
+	Name       string
+	Package    string
+	Suggestion string
+}
+
+func (t TypeNotFoundErr) Error() string {
+	msg := fmt.Sprintf("%s not found in declared types of %s", t.Name, t.Package)
+	if t.Suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %s?", t.Suggestion)
+	}
+	return msg
+}
+
+// NoMatchingInterfaceErr represents a glob such as *Repository that doesn't
+// match any interface of its package.
+type NoMatchingInterfaceErr struct {
+	Pattern string
+	Package string
+}
+
+func (n NoMatchingInterfaceErr) Error() string {
+	return fmt.Sprintf("no interfaces matching %s found in %s", n.Pattern, n.Package)
+}
+
+// AmbiguousMiddlewareErr represents a middleware type named for a glob
+// matching several interfaces, which can't all share it.
+type AmbiguousMiddlewareErr struct {
+	Middleware string
+	Pattern    string
+	Matches    []string
+}
+
+func (a AmbiguousMiddlewareErr) Error() string {
+	return fmt.Sprintf("middleware %s is named for %s, which matches several interfaces: %s",
+		a.Middleware, a.Pattern, strings.Join(a.Matches, ", "))
+}
+
+// UnknownCustomizerErr represents a customizer that was never registered,
+// nor found on the PATH as an external plugin.
+type UnknownCustomizerErr struct {
+	Name string
+}
+
+func (u UnknownCustomizerErr) Error() string {
+	return fmt.Sprintf("no customizer found by name %s, nor a middleware-gen-%s plugin on the PATH", u.Name, u.Name)
+}
+
+// PluginErr represents an external plugin that failed, or responded with
+// an error or with code that doesn't compile.
+type PluginErr struct {
+	Name string
+	Type string
+	Err  error
+}
+
+func (p PluginErr) Error() string {
+	return fmt.Sprintf("plugin middleware-gen-%s generating %s: %v", p.Name, p.Type, p.Err)
+}
+
+func (p PluginErr) Unwrap() error {
+	return p.Err
+}
+
+// TemplateErr represents a template customizer that failed to execute its
+// file, or whose sections didn't render a valid middleware.
+type TemplateErr struct {
+	Path string
+	Type string
+	Err  error
+}
+
+func (t TemplateErr) Error() string {
+	return fmt.Sprintf("template %s generating %s: %v", t.Path, t.Type, t.Err)
+}
+
+func (t TemplateErr) Unwrap() error {
+	return t.Err
+}
+
+// CustomizerOptionErr represents options that a customizer could not be
+// configured with.
+type CustomizerOptionErr struct {
+	Name string
+	Err  error
+}
+
+func (c CustomizerOptionErr) Error() string {
+	return fmt.Sprintf("customizer %s: %v", c.Name, c.Err)
+}
+
+func (c CustomizerOptionErr) Unwrap() error {
+	return c.Err
+}
+
+// DuplicateMiddlewareErr represents several customizers generating the same
+// struct for an interface, which would fail to compile.
+type DuplicateMiddlewareErr struct {
+	Struct string
+	Type   string
+}
+
+func (d DuplicateMiddlewareErr) Error() string {
+	return fmt.Sprintf("more than one customizer generates %s for %s", d.Struct, d.Type)
+}
+
+// TypeTooComplexErr represents a type nested too deeply to be interpreted.
+type TypeTooComplexErr struct {
+	Type types.Type
+}
+
+func (t TypeTooComplexErr) Error() string {
+	return fmt.Sprintf("type %v is nested too deeply to interpret", t.Type)
+}
+
+// InvalidOptionsErr represents options that don't describe what to generate.
+type InvalidOptionsErr struct {
+	Reason string
+}
+
+func (i InvalidOptionsErr) Error() string {
+	return i.Reason
+}
+
+// ConfigErr represents a configuration file that cannot be read or that
+// doesn't describe what to generate.
+type ConfigErr struct {
+	Path string
+	Err  error
+}
+
+func (c ConfigErr) Error() string {
+	return fmt.Sprintf("%s: %v", c.Path, c.Err)
+}
+
+func (c ConfigErr) Unwrap() error {
+	return c.Err
 }
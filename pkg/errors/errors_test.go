@@ -0,0 +1,43 @@
+package errors_test
+
+import (
+	goerrors "errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"testing"
+
+	"github.com/gabizou/middleware-generator/pkg/errors"
+)
+
+func TestListAs(t *testing.T) {
+	unexported := errors.UnexportedTypeErr{
+		Obj: types.NewTypeName(token.NoPos, types.NewPackage("example.com/store", "store"), "user", nil),
+		Pos: token.Position{Filename: "store.go", Line: 12, Column: 2},
+	}
+	list := errors.List{
+		errors.InvalidOptionsErr{Reason: "invalid"},
+		fmt.Errorf("loading: %w", unexported),
+		fs.ErrNotExist,
+	}
+	err := fmt.Errorf("generating: %w", list)
+
+	var found errors.UnexportedTypeErr
+	if !goerrors.As(err, &found) {
+		t.Fatalf("%v holds no UnexportedTypeErr", err)
+	}
+	if found.Error() != unexported.Error() {
+		t.Errorf("found %v, want %v", found, unexported)
+	}
+	var notFound errors.NotAnInterfaceErr
+	if goerrors.As(err, &notFound) {
+		t.Errorf("found %v in %v", notFound, err)
+	}
+	if !goerrors.Is(err, fs.ErrNotExist) {
+		t.Errorf("%v is not fs.ErrNotExist", err)
+	}
+	if goerrors.Is(err, fs.ErrPermission) {
+		t.Errorf("%v is fs.ErrPermission", err)
+	}
+}
@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gabizou/middleware-generator/pkg/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the middleware of a whole project, as read from a
+// middleware.yaml or JSON file by LoadConfig:
+//
+//	header: Copyright (c) Acme
+//	packages:
+//	  - dir: ./store
+//	    plugins: [tracer, logger]
+//	    interfaces:
+//	      - type: Repository
+//	        middleware: RepoMiddleware
+//	        plugins:
+//	          - name: tracer
+//	            options: {root: "true"}
+//	        output: tracer_repo.go
+type Config struct {
+	// Header is an additional comment for the header of the generated files.
+	Header   string          `yaml:"header"`
+	Packages []PackageConfig `yaml:"packages"`
+	// Path is the configuration file, as reported by errors.
+	Path string `yaml:"-"`
+	// Dir is the directory the Dir of each package is relative to, which
+	// is that of the configuration file.
+	Dir string `yaml:"-"`
+	// Args are recorded in the header of the generated files.
+	Args []string `yaml:"-"`
+}
+
+// PackageConfig lists the interfaces of the package in Dir to generate
+// middleware for, along with the defaults for each of them. An Output file,
+// rather than a directory, is generated for a single interface, whether
+// inherited from the package or its own.
+type PackageConfig struct {
+	Dir        string            `yaml:"dir"`
+	Plugins    []PluginConfig    `yaml:"plugins"`
+	Combine    bool              `yaml:"combine"`
+	Output     string            `yaml:"output"`
+	Interfaces []InterfaceConfig `yaml:"interfaces"`
+}
+
+// InterfaceConfig is an interface to generate middleware for, which may be a
+// qualified reference or a glob as the -type flag is. Its Plugins replace
+// those of the package, and so do its Combine when set and its Output.
+type InterfaceConfig struct {
+	Type       string         `yaml:"type"`
+	Middleware string         `yaml:"middleware"`
+	Plugins    []PluginConfig `yaml:"plugins"`
+	Combine    *bool          `yaml:"combine"`
+	Output     string         `yaml:"output"`
+}
+
+// PluginConfig is a customizer along with its options, written either as
+// the name[:key=value,...] of the -plugin flag or as a name and options.
+type PluginConfig struct {
+	Name    string            `yaml:"name"`
+	Options map[string]string `yaml:"options"`
+}
+
+// UnmarshalYAML accepts both forms of a PluginConfig.
+func (p *PluginConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		name, options, err := parseCustomizerSpec(value.Value)
+		if err != nil {
+			return err
+		}
+		p.Name, p.Options = name, options
+		return nil
+	}
+	// the decoding of a node doesn't reject unknown keys as LoadConfig does
+	for k := 0; value.Kind == yaml.MappingNode && k < len(value.Content); k += 2 {
+		if key := value.Content[k]; key.Value != "name" && key.Value != "options" {
+			return fmt.Errorf("line %d: field %s not found in type generator.PluginConfig", key.Line, key.Value)
+		}
+	}
+	type plain PluginConfig
+	return value.Decode((*plain)(p))
+}
+
+// spec formats the plugin as name:key=value,... for SetupCustomizer.
+func (p PluginConfig) spec() string {
+	if len(p.Options) == 0 {
+		return p.Name
+	}
+	options := make([]string, 0, len(p.Options))
+	for key, value := range p.Options {
+		options = append(options, key+"="+value)
+	}
+	sort.Strings(options)
+	return p.Name + ":" + strings.Join(options, ",")
+}
+
+// LoadConfig reads the configuration file, which is YAML or JSON.
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.ConfigErr{Path: path, Err: err}
+	}
+	config := &Config{Path: path, Dir: filepath.Dir(path)}
+	// JSON is read as the YAML it is a subset of, rejecting unknown keys
+	// such as a misspelled plugin: for plugins:
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, errors.ConfigErr{Path: path, Err: err}
+	}
+	if len(config.Packages) == 0 {
+		return nil, errors.ConfigErr{Path: path, Err: fmt.Errorf("no packages to generate for")}
+	}
+	return config, nil
+}
+
+// GenerateConfig renders the middleware of every interface of the Config
+// without writing anything, loading each of its packages only once. See
+// Generate.
+func GenerateConfig(ctx context.Context, config *Config) ([]GeneratedFile, error) {
+	files, err := config.files()
+	if err != nil {
+		return nil, err
+	}
+	return generateFiles(ctx, files)
+}
+
+// files creates a File for each interface of the Config, validated as the
+// Options of Generate are.
+func (c *Config) files() ([]*File, error) {
+	var files []*File
+	// outputs are the interfaces by the file they are generated into, as
+	// the files generated for another interface would be overwritten
+	outputs := make(map[string]string)
+	for p, pkg := range c.Packages {
+		dir := pkg.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(c.Dir, dir)
+		}
+		if len(pkg.Interfaces) == 0 {
+			return nil, errors.ConfigErr{Path: c.Path, Err: fmt.Errorf("packages[%d] lists no interfaces", p)}
+		}
+		for i, iface := range pkg.Interfaces {
+			plugins, combine, output := iface.Plugins, pkg.Combine, iface.Output
+			if len(plugins) == 0 {
+				plugins = pkg.Plugins
+			}
+			if iface.Combine != nil {
+				combine = *iface.Combine
+			}
+			if output == "" {
+				output = pkg.Output
+			}
+			opts := Options{
+				Dir:     dir,
+				Types:   []string{iface.Type},
+				Combine: combine,
+				Output:  output,
+				Header:  c.Header,
+				Args:    c.Args,
+			}
+			if iface.Type == "" {
+				opts.Types = nil
+			}
+			if iface.Middleware != "" {
+				opts.Middlewares = []string{iface.Middleware}
+			}
+			for _, plugin := range plugins {
+				opts.Plugins = append(opts.Plugins, plugin.spec())
+			}
+			interfaceFiles, err := opts.files()
+			if err != nil {
+				return nil, errors.ConfigErr{Path: c.Path, Err: fmt.Errorf("packages[%d].interfaces[%d]: %w", p, i, err)}
+			}
+			if outDir, name := splitOutput(dir, output); name != "" {
+				interfacePath := fmt.Sprintf("packages[%d].interfaces[%d]", p, i)
+				path := filepath.Join(outDir, name)
+				if other, ok := outputs[path]; ok {
+					return nil, errors.ConfigErr{
+						Path: c.Path,
+						Err:  fmt.Errorf("%s: output %s is already generated for %s", interfacePath, output, other),
+					}
+				}
+				outputs[path] = interfacePath
+			}
+			for _, file := range interfaceFiles {
+				file.PluginDir = c.Dir
+			}
+			files = append(files, interfaceFiles...)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gabizou/middleware-generator/pkg/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPluginConfigUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    PluginConfig
+		spec    string
+		wantErr bool
+	}{
+		{src: `tracer`, want: PluginConfig{Name: "tracer"}, spec: "tracer"},
+		{
+			src:  `template:name=audit,file=audit.tmpl`,
+			want: PluginConfig{Name: "template", Options: map[string]string{"name": "audit", "file": "audit.tmpl"}},
+			spec: "template:file=audit.tmpl,name=audit",
+		},
+		{
+			src:  `{name: tracer, options: {root: "true"}}`,
+			want: PluginConfig{Name: "tracer", Options: map[string]string{"root": "true"}},
+			spec: "tracer:root=true",
+		},
+		{src: `{name: logger}`, want: PluginConfig{Name: "logger"}, spec: "logger"},
+		{
+			src:  `tracer:root`,
+			want: PluginConfig{Name: "tracer", Options: map[string]string{"root": "true"}},
+			spec: "tracer:root=true",
+		},
+		{src: `template:=audit.tmpl`, wantErr: true},
+		{src: `[tracer, logger]`, wantErr: true},
+	}
+	for _, test := range tests {
+		var got PluginConfig
+		err := yaml.Unmarshal([]byte(test.src), &got)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %+v, want an error", test.src, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.src, got, test.want)
+		}
+		if spec := got.spec(); spec != test.spec {
+			t.Errorf("%s: spec %s, want %s", test.src, spec, test.spec)
+		}
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	src := `
+header: Copyright (c) Acme
+packages:
+  - dir: ./store
+    plugins: [tracer, logger]
+    combine: true
+    output: gen/
+    interfaces:
+      - type: Repository
+        middleware: RepoMiddleware
+      - type: Cache
+        plugins:
+          - name: tracer
+            options: {root: "true"}
+        combine: false
+        output: tracer_cache.go
+  - dir: /abs/service
+    plugins: [logger]
+    interfaces:
+      - type: Service
+`
+	config := &Config{Path: "conf/middleware.yaml", Dir: "conf", Args: []string{"-config", "conf/middleware.yaml"}}
+	if err := yaml.Unmarshal([]byte(src), config); err != nil {
+		t.Fatal(err)
+	}
+	files, err := config.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*File{
+		{
+			Directory:   filepath.Join("conf", "store"),
+			TypeName:    "Repository",
+			Middleware:  "RepoMiddleware",
+			Customizers: []string{"tracer", "logger"},
+			Combine:     true,
+			Output:      "gen/",
+		},
+		{
+			Directory:   filepath.Join("conf", "store"),
+			TypeName:    "Cache",
+			Customizers: []string{"tracer:root=true"},
+			Output:      "tracer_cache.go",
+		},
+		{
+			Directory:   "/abs/service",
+			TypeName:    "Service",
+			Customizers: []string{"logger"},
+		},
+	}
+	for _, file := range want {
+		file.Args, file.Header, file.PluginDir = config.Args, config.Header, config.Dir
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got\n%s\nwant\n%s", describeFiles(files), describeFiles(want))
+	}
+}
+
+func TestConfigFilesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "no interfaces",
+			src:  "packages:\n  - dir: store\n    plugins: [tracer]",
+			want: "middleware.yaml: packages[0] lists no interfaces",
+		},
+		{
+			name: "no plugins",
+			src:  "packages:\n  - dir: store\n    interfaces:\n      - type: Repository",
+			want: "middleware.yaml: packages[0].interfaces[0]: a type and at least one plugin are required",
+		},
+		{
+			name: "no type",
+			src:  "packages:\n  - dir: store\n    plugins: [tracer]\n    interfaces:\n      - middleware: RepoMiddleware",
+			want: "middleware.yaml: packages[0].interfaces[0]: a type and at least one plugin are required",
+		},
+		{
+			name: "output of several plugins",
+			src:  "packages:\n  - dir: store\n    plugins: [tracer, logger]\n    interfaces:\n      - type: Repository\n        output: repo.go",
+			want: "middleware.yaml: packages[0].interfaces[0]: an output file is only supported",
+		},
+		{
+			name: "output file of the package shared by its interfaces",
+			src:  "packages:\n  - dir: store\n    plugins: [tracer, logger]\n    combine: true\n    output: all.go\n    interfaces:\n      - type: Repository\n      - type: Cache",
+			want: "middleware.yaml: packages[0].interfaces[1]: output all.go is already generated for packages[0].interfaces[0]",
+		},
+		{
+			name: "output file of interfaces of several packages",
+			src: "packages:\n  - dir: store\n    plugins: [tracer]\n    interfaces:\n      - type: Repository\n        output: ../gen/tracer.go\n" +
+				"  - dir: service\n    plugins: [tracer]\n    interfaces:\n      - type: Service\n        output: ../gen/tracer.go",
+			want: "middleware.yaml: packages[1].interfaces[0]: output ../gen/tracer.go is already generated for packages[0].interfaces[0]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &Config{Path: "middleware.yaml", Dir: "."}
+			if err := yaml.Unmarshal([]byte(test.src), config); err != nil {
+				t.Fatal(err)
+			}
+			_, err := config.files()
+			configErr, ok := err.(errors.ConfigErr)
+			if !ok {
+				t.Fatalf("got %T %v, want ConfigErr", err, err)
+			}
+			if !strings.HasPrefix(configErr.Error(), test.want) {
+				t.Errorf("got %s, want %s", configErr, test.want)
+			}
+		})
+	}
+}
+
+func TestConfigFilesSharedOutputDir(t *testing.T) {
+	src := "packages:\n  - dir: store\n    plugins: [tracer]\n    output: gen/\n    interfaces:\n      - type: Repository\n      - type: Cache"
+	config := &Config{Path: "middleware.yaml", Dir: "."}
+	if err := yaml.Unmarshal([]byte(src), config); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.files(); err != nil {
+		t.Errorf("an output directory is shared: %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want is the error, if any
+		want string
+	}{
+		{
+			name: "yaml",
+			src:  "packages:\n  - dir: store\n    plugins: [tracer]\n    interfaces:\n      - type: Repository",
+		},
+		{
+			name: "json",
+			src:  `{"packages": [{"dir": "store", "plugins": ["tracer"], "interfaces": [{"type": "Repository"}]}]}`,
+		},
+		{
+			name: "empty",
+			src:  "",
+			want: "no packages to generate for",
+		},
+		{
+			name: "misspelled key of a package",
+			src:  "packages:\n  - dir: store\n    plugin: [tracer]\n    interfaces:\n      - type: Repository",
+			want: "line 3: field plugin not found in type generator.PackageConfig",
+		},
+		{
+			name: "misspelled key of an interface",
+			src:  "packages:\n  - dir: store\n    plugins: [tracer]\n    interfaces:\n      - type: Repository\n        middlware: RepoMiddleware",
+			want: "line 6: field middlware not found in type generator.InterfaceConfig",
+		},
+		{
+			name: "misspelled key of a plugin",
+			src:  "packages:\n  - dir: store\n    plugins:\n      - name: tracer\n        option: {root: true}\n    interfaces:\n      - type: Repository",
+			want: "line 5: field option not found in type generator.PluginConfig",
+		},
+		{
+			name: "misspelled key of json",
+			src:  `{"packages": [{"dir": "store", "plugins": ["tracer"], "interface": [{"type": "Repository"}]}]}`,
+			want: "field interface not found in type generator.PackageConfig",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "middleware.yaml")
+			if err := os.WriteFile(path, []byte(test.src), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadConfig(path)
+			if test.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(config.Packages) != 1 || len(config.Packages[0].Interfaces) != 1 {
+					t.Errorf("got %+v, want a package with an interface", config.Packages)
+				}
+				return
+			}
+			if _, ok := err.(errors.ConfigErr); !ok || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %T %v, want a ConfigErr containing %s", err, err, test.want)
+			}
+		})
+	}
+}
+
+func describeFiles(files []*File) string {
+	lines := make([]string, len(files))
+	for i, file := range files {
+		lines[i] = fmt.Sprintf("%+v", *file)
+	}
+	return strings.Join(lines, "\n")
+}
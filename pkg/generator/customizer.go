@@ -2,9 +2,12 @@ package generator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/dave/jennifer/jen"
+	"github.com/gabizou/middleware-generator/pkg/errors"
 	"github.com/gabizou/middleware-generator/pkg/interpreter"
 )
 
@@ -25,15 +28,70 @@ type Customizer interface {
 	// of pre-computed code statements as the function declaration
 	// as described by the passed-in DeclaredFunction. It is important
 	// to note that no code blocks have been created at this point, see
-	// jen.Block for more details.
+	// jen.Block for more details. Methods skipping the customizer by
+	// //middleware:skip are forwarded along without being passed in, while
+	// the other Directives of the method are left to the customizer.
 	GenerateFunctionImplementation(builder *jen.Statement, service *ServiceModel, method interpreter.DeclaredFunction) jen.Code
 }
 
+// FactoryCustomizer is optionally implemented by a Customizer that needs
+// to prepare state in the generated factory method before the middleware
+// is returned, such as registering collectors. Each of the
+// ServiceModel.StructFields must be declared here as a local variable.
+type FactoryCustomizer interface {
+	GenerateFactoryImplementation(group *jen.Group, service *ServiceModel)
+}
+
+// ConfigurableCustomizer is optionally implemented by a Customizer that
+// accepts options, which are given after its name as in
+//
+//	tracer:root=true,name={interface}.{method}
+type ConfigurableCustomizer interface {
+	Customizer
+	// WithOptions returns a copy of the Customizer configured by the given
+	// options, or an error when an option is unknown or malformed.
+	WithOptions(options map[string]string) (Customizer, error)
+}
+
+// namedCustomizer is implemented by a Customizer whose methods are skipped
+// by //middleware:skip under a name other than the one it is looked up by,
+// such as a template customizer named after its file.
+type namedCustomizer interface {
+	customizerName() string
+}
+
+// MiddlewareParameter describes a parameter of the generated factory method.
+// Parameters with an empty FieldName are not stored on the generated struct.
 type MiddlewareParameter struct {
 	VariableName string
 	TypeName     string
 	TypePath     string
 	FieldName    string
+	// Pointer declares the parameter as a pointer to the named type.
+	Pointer bool
+}
+
+func (m MiddlewareParameter) typeCode() jen.Code {
+	code := jen.Null()
+	if m.Pointer {
+		code.Op("*")
+	}
+	if m.TypePath != "" {
+		return code.Qual(m.TypePath, m.TypeName)
+	}
+	return code.Id(m.TypeName)
+}
+
+// ForwardCall creates the call to the wrapped service for the given method,
+// passing along every parameter as it was received:
+//
+//	${StructPtr}.${ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+func (s *ServiceModel) ForwardCall(method interpreter.DeclaredFunction) *jen.Statement {
+	var methodParams []jen.Code
+	for _, p := range method.Parameters() {
+		methodParams = append(methodParams, p.NamedParameter())
+	}
+	return jen.Id(s.StructPtr).Dot(s.ServicePtr).Dot(method.Name()).Call(methodParams...)
 }
 
 var (
@@ -55,11 +113,83 @@ func Register(name string, customizer Customizer) {
 	customizers[name] = customizer
 }
 
-func (g *Generator) SetupCustomizer(customizer string) {
+// Customizers lists the names of every registered Customizer.
+func Customizers() []string {
+	customizersMu.RLock()
+	defer customizersMu.RUnlock()
+	names := make([]string, 0, len(customizers))
+	for name := range customizers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetupCustomizer looks up the registered Customizer of each spec by name,
+// or else the external plugin on the PATH, see package plugin. It is
+// configured with any options following the name, see
+// ConfigurableCustomizer. The model is generated with each of them in turn.
+func (g *Generator) SetupCustomizer(specs ...string) error {
+	for _, spec := range specs {
+		customizer, err := lookupCustomizer(spec)
+		if err != nil {
+			return err
+		}
+		name, _, _ := strings.Cut(spec, ":")
+		if named, ok := customizer.(namedCustomizer); ok {
+			name = named.customizerName()
+		}
+		g.customizers = append(g.customizers, customizer)
+		g.customizerNames = append(g.customizerNames, name)
+	}
+	return nil
+}
+
+func lookupCustomizer(spec string) (Customizer, error) {
+	name, options, err := parseCustomizerSpec(spec)
+	if err != nil {
+		return nil, err
+	}
 	customizersMu.RLock()
 	defer customizersMu.RUnlock()
-	g.customizer = customizers[customizer]
-	if g.customizer == nil {
-		panic(fmt.Errorf("generator: No customizer found by name %s", customizer))
+	customizer := customizers[name]
+	if customizer == nil {
+		customizer = lookupExternal(name)
+	}
+	if customizer == nil {
+		return nil, errors.UnknownCustomizerErr{Name: name}
+	}
+	if len(options) == 0 {
+		return customizer, nil
+	}
+	configurable, ok := customizer.(ConfigurableCustomizer)
+	if !ok {
+		return nil, errors.CustomizerOptionErr{Name: name, Err: fmt.Errorf("does not accept options")}
+	}
+	customizer, err = configurable.WithOptions(options)
+	if err != nil {
+		return nil, errors.CustomizerOptionErr{Name: name, Err: err}
+	}
+	return customizer, nil
+}
+
+// parseCustomizerSpec splits name:key=value,key=value into the
+// customizer name and its options. A key without a value is "true".
+func parseCustomizerSpec(spec string) (string, map[string]string, error) {
+	name, rawOptions, found := strings.Cut(spec, ":")
+	if !found || rawOptions == "" {
+		return name, nil, nil
+	}
+	options := make(map[string]string)
+	for _, option := range strings.Split(rawOptions, ",") {
+		key, value, hasValue := strings.Cut(option, "=")
+		if key == "" {
+			return "", nil, errors.CustomizerOptionErr{Name: name, Err: fmt.Errorf("malformed option %q", option)}
+		}
+		if !hasValue {
+			value = "true"
+		}
+		options[key] = value
 	}
+	return name, options, nil
 }
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/gabizou/middleware-generator/pkg/errors"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// discover loads the packages matching the Patterns of the Options, creating
+// a File for every interface annotated by //middleware:generate in its doc
+// comment, such as:
+//
+//	//middleware:generate tracer,logger
+//	type Repository interface { ... }
+//
+// The middleware is generated into the package declaring the interface, for
+// the func(X) X type declared there, or else declaring one. A plugin with
+// options is written as with -plugin, e.g. tracer:root=true,name={method}.
+// The returned caches hold the loaded packages for interpreting the Files.
+func (o Options) discover(ctx context.Context) ([]*File, *packageCaches, error) {
+	dir := o.Dir
+	if dir == "" {
+		dir = "."
+	}
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packageLoadingMode,
+		Dir:     dir,
+	}
+	pkgs, err := packages.Load(cfg, o.Patterns...)
+	if err != nil {
+		return nil, nil, errors.PackageLoadErr{Dir: dir, Pattern: strings.Join(o.Patterns, " "), Err: err}
+	}
+	caches := newPackageCaches(ctx)
+	var files []*File
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		pkgDir := packageDir(pkg, dir)
+		caches.of(pkgDir).seed(pkg)
+		for _, annotated := range annotatedInterfaces(pkg) {
+			files = append(files, &File{
+				Directory:   pkgDir,
+				TypeName:    annotated.name,
+				Customizers: annotated.plugins,
+				Combine:     o.Combine,
+				Args:        o.Args,
+				Header:      o.Header,
+				PluginDir:   pkgDir,
+			})
+		}
+	}
+	return files, caches, nil
+}
+
+type annotatedInterface struct {
+	name    string
+	plugins []string
+}
+
+// annotatedInterfaces finds the types of the package carrying a
+// //middleware:generate directive, in the order they are declared.
+func annotatedInterfaces(pkg *packages.Package) []annotatedInterface {
+	var annotated []annotatedInterface
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				plugins := pluginSpecs(interpreter.ParseDirectives(doc).Values("generate"))
+				if len(plugins) > 0 {
+					annotated = append(annotated, annotatedInterface{name: typeSpec.Name.Name, plugins: plugins})
+				}
+			}
+		}
+	}
+	return annotated
+}
+
+// pluginSpecs joins the options that were split from their plugin along
+// with the plugins, as both are separated by commas.
+func pluginSpecs(args []string) []string {
+	var specs []string
+	for _, arg := range args {
+		if len(specs) > 0 && strings.Contains(arg, "=") && !strings.Contains(arg, ":") {
+			specs[len(specs)-1] += "," + arg
+			continue
+		}
+		specs = append(specs, arg)
+	}
+	return specs
+}
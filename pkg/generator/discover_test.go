@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestPluginSpecs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: nil, want: nil},
+		{args: []string{"tracer", "logger"}, want: []string{"tracer", "logger"}},
+		{
+			args: []string{"tracer:root=true", "name={method}", "logger"},
+			want: []string{"tracer:root=true,name={method}", "logger"},
+		},
+		{
+			args: []string{"logger", "template:file=audit.tmpl", "name=audit"},
+			want: []string{"logger", "template:file=audit.tmpl,name=audit"},
+		},
+		{args: []string{"level=debug", "logger"}, want: []string{"level=debug", "logger"}},
+	}
+	for _, test := range tests {
+		if got := pluginSpecs(test.args); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("pluginSpecs(%q) = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestAnnotatedInterfaces(t *testing.T) {
+	src := `package store
+
+//middleware:generate tracer,logger
+type Repository interface{}
+
+// Cache is annotated by its own doc.
+type (
+	//middleware:generate tracer:root=true,name={method}
+	Cache interface{}
+
+	// Store is not annotated, as the doc of the declaration is shared.
+	Store interface{}
+)
+
+//middleware:generate logger
+type (
+	Users interface{}
+	Roles interface{}
+)
+
+// Plain is not annotated.
+type Plain interface{}
+
+//middleware:generate
+type Empty interface{}
+
+//middleware:generate template:file=audit.tmpl,name=audit
+//middleware:generate logger
+type Audited interface{}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "store.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := annotatedInterfaces(&packages.Package{Syntax: []*ast.File{file}})
+	want := []annotatedInterface{
+		{name: "Repository", plugins: []string{"tracer", "logger"}},
+		{name: "Cache", plugins: []string{"tracer:root=true,name={method}"}},
+		{name: "Audited", plugins: []string{"template:file=audit.tmpl,name=audit", "logger"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
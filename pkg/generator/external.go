@@ -0,0 +1,394 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/gabizou/middleware-generator/pkg/errors"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+	"github.com/gabizou/middleware-generator/pkg/plugin"
+)
+
+// externalPrefix prefixes the name of the executable of an external plugin.
+const externalPrefix = "middleware-gen-"
+
+// preparedCustomizer is implemented by a Customizer generating the whole
+// middleware of a model at once, such as an external plugin. The Customizer
+// it prepares is used for the model instead.
+type preparedCustomizer interface {
+	prepare(g *Generator, model *ServiceModel) (Customizer, error)
+}
+
+// external is the Customizer of an external plugin, a middleware-gen-<name>
+// executable on the PATH speaking the protocol of package plugin.
+type external struct {
+	name    string
+	path    string
+	options map[string]string
+}
+
+// lookupExternal finds the executable of the external plugin by the name,
+// if any.
+func lookupExternal(name string) Customizer {
+	if !token.IsIdentifier(name) {
+		return nil
+	}
+	path, err := exec.LookPath(externalPrefix + name)
+	if err != nil {
+		return nil
+	}
+	return external{name: name, path: path}
+}
+
+func (e external) FileNamePrefix() string {
+	return e.name
+}
+
+func (e external) FactorySuffix() string {
+	return factorySuffix(e.name)
+}
+
+func (e external) ConfigureModel(model *ServiceModel) {
+	model.StructPrefix = e.name + "%s"
+}
+
+// factorySuffix capitalizes the name of a plugin.
+func factorySuffix(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+func (e external) GetRequiredImportNames() map[string]string {
+	return nil
+}
+
+// GenerateFunctionImplementation forwards the call untouched, as the
+// methods are generated by the Customizer prepared for the model.
+func (e external) GenerateFunctionImplementation(
+	builder *jen.Statement,
+	service *ServiceModel,
+	method interpreter.DeclaredFunction,
+) jen.Code {
+	return forwardBlock(builder, service, method)
+}
+
+// WithOptions passes the options along to the plugin, which validates them.
+func (e external) WithOptions(options map[string]string) (Customizer, error) {
+	e.options = options
+	return e, nil
+}
+
+// prepare runs the plugin on the model, checking the code it responds with.
+// The plugin is killed once the context of the generation is done.
+func (e external) prepare(g *Generator, model *ServiceModel) (Customizer, error) {
+	fail := func(err error) (Customizer, error) {
+		return nil, errors.PluginErr{Name: e.name, Type: model.TypeName, Err: err}
+	}
+	req := pluginRequest(g, model, e.name, e.options)
+	in, err := json.Marshal(req)
+	if err != nil {
+		return fail(err)
+	}
+	var stdout, stderr bytes.Buffer
+	ctx := g.context()
+	cmd := exec.CommandContext(ctx, e.path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fail(err)
+	}
+	var resp plugin.Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fail(fmt.Errorf("reading response: %w", err))
+	}
+	if resp.Error != "" {
+		return fail(goerrors.New(resp.Error))
+	}
+	prepared, err := respondedPlugin(e.name, req, resp)
+	if err != nil {
+		return fail(err)
+	}
+	return prepared, nil
+}
+
+// respondedPlugin creates the Customizer generating what a plugin responded
+// with, checking that its code parses.
+func respondedPlugin(name string, req plugin.Request, resp plugin.Response) (Customizer, error) {
+	if err := checkStatements("factory", resp.Factory); err != nil {
+		return nil, err
+	}
+	for method, body := range resp.Methods {
+		if err := checkStatements("method "+method, body); err != nil {
+			return nil, err
+		}
+	}
+	imports, err := importPaths(req.Imports, resp.Imports)
+	if err != nil {
+		return nil, err
+	}
+	return generatedPlugin{name: name, resp: resp, imports: imports}, nil
+}
+
+// importPaths indexes the import paths of the packages by their names,
+// which must each name a single package.
+func importPaths(imports ...map[string]string) (map[string]string, error) {
+	paths := make(map[string]string)
+	for _, names := range imports {
+		for path, name := range names {
+			if other, ok := paths[name]; ok && other != path {
+				return nil, fmt.Errorf("imports: %s names both %s and %s", name, other, path)
+			}
+			paths[name] = path
+		}
+	}
+	return paths, nil
+}
+
+// pluginRequest describes the model to the plugin by the name, with the
+// types of the methods qualified by the names of their packages. Packages
+// sharing a name are told apart by a numbered alias, e.g. v1 and v12.
+func pluginRequest(g *Generator, model *ServiceModel, name string, options map[string]string) plugin.Request {
+	configured := *model
+	configured.StructPrefix = name + "%s"
+	structName := fmt.Sprintf(configured.StructPrefix, model.TypeName)
+	configured.StructPtr = receiverName(structName)
+	configured.ServicePtr = receiverName(model.TypeName)
+
+	imports := make(map[string]string)
+	aliased := make(map[string]bool)
+	qualifier := func(pkg *types.Package) string {
+		if pkg.Path() == g.pkgPath {
+			return ""
+		}
+		if alias, ok := imports[pkg.Path()]; ok {
+			return alias
+		}
+		alias := pkg.Name()
+		for i := 2; aliased[alias]; i++ {
+			alias = fmt.Sprintf("%s%d", pkg.Name(), i)
+		}
+		imports[pkg.Path()] = alias
+		aliased[alias] = true
+		return alias
+	}
+	variable := func(name string, v interpreter.NamedVariable) plugin.Variable {
+		return plugin.Variable{Name: name, Type: types.TypeString(v.UnderlyingType(), qualifier)}
+	}
+	methods := make([]plugin.Method, 0, len(model.Interface))
+	for _, method := range model.Interface {
+		if method.Directives().Skips(name) {
+			continue
+		}
+		m := plugin.Method{
+			Name:     method.Name(),
+			Variadic: method.Variadic(),
+			HasError: method.ReturnsError(),
+			Call:     fmt.Sprintf("%#v", configured.ForwardCall(method)),
+		}
+		for _, p := range method.Parameters() {
+			m.Params = append(m.Params, variable(p.Name(), p))
+		}
+		for i, name := range method.ResultNames() {
+			m.Results = append(m.Results, variable(name, method.Returns()[i]))
+		}
+		if ctx := method.ContextParameter(); ctx != nil {
+			m.Context = ctx.Name()
+		}
+		for _, d := range method.Directives() {
+			m.Directives = append(m.Directives, plugin.Directive{Name: d.Name, Args: d.Args})
+		}
+		methods = append(methods, m)
+	}
+	return plugin.Request{
+		Version: plugin.Version,
+		Name:    name,
+		Options: options,
+		Service: plugin.Service{
+			TypeName:   model.TypeName,
+			Package:    g.pkgName,
+			StructName: structName,
+			StructPtr:  configured.StructPtr,
+			ServicePtr: configured.ServicePtr,
+		},
+		Methods: methods,
+		Imports: imports,
+	}
+}
+
+// generatedPlugin is the Customizer of a plugin prepared for a model,
+// generating what the plugin responded with.
+type generatedPlugin struct {
+	name string
+	resp plugin.Response
+	// imports are the import paths of the packages by their names
+	imports map[string]string
+}
+
+func (p generatedPlugin) FileNamePrefix() string {
+	if p.resp.FileNamePrefix != "" {
+		return p.resp.FileNamePrefix
+	}
+	return p.name
+}
+
+func (p generatedPlugin) FactorySuffix() string {
+	if p.resp.FactorySuffix != "" {
+		return p.resp.FactorySuffix
+	}
+	return factorySuffix(p.name)
+}
+
+func (p generatedPlugin) ConfigureModel(model *ServiceModel) {
+	model.StructPrefix = p.name + "%s"
+	model.InputParameters = middlewareParameters(p.resp.Parameters)
+	model.StructFields = middlewareParameters(p.resp.Fields)
+}
+
+func (p generatedPlugin) GetRequiredImportNames() map[string]string {
+	return p.resp.Imports
+}
+
+// GenerateFactoryImplementation adds the factory statements of the plugin.
+func (p generatedPlugin) GenerateFactoryImplementation(group *jen.Group, service *ServiceModel) {
+	if p.resp.Factory == "" {
+		return
+	}
+	params := make([]string, len(service.InputParameters))
+	for i, param := range service.InputParameters {
+		params[i] = param.VariableName
+	}
+	group.Add(qualifiedCode(p.resp.Factory, p.imports, params))
+}
+
+// GenerateFunctionImplementation adds the body the plugin generated for the
+// method, or forwards the call untouched when there is none.
+func (p generatedPlugin) GenerateFunctionImplementation(
+	builder *jen.Statement,
+	service *ServiceModel,
+	method interpreter.DeclaredFunction,
+) jen.Code {
+	body, ok := p.resp.Methods[method.Name()]
+	if !ok {
+		return forwardBlock(builder, service, method)
+	}
+	var variables []string
+	for _, param := range method.Parameters() {
+		variables = append(variables, param.Name())
+	}
+	variables = append(variables, method.ResultNames()...)
+	return builder.Block(qualifiedCode(body, p.imports, variables))
+}
+
+func middlewareParameters(params []plugin.Parameter) []MiddlewareParameter {
+	converted := make([]MiddlewareParameter, len(params))
+	for i, p := range params {
+		converted[i] = MiddlewareParameter{
+			VariableName: p.Name,
+			TypeName:     p.Type,
+			TypePath:     p.Path,
+			FieldName:    p.Field,
+			Pointer:      p.Pointer,
+		}
+	}
+	return converted
+}
+
+// forwardBlock creates a body forwarding the call untouched.
+func forwardBlock(builder *jen.Statement, service *ServiceModel, method interpreter.DeclaredFunction) jen.Code {
+	call := service.ForwardCall(method)
+	if len(method.Returns()) > 0 {
+		return builder.Block(jen.Return(call))
+	}
+	return builder.Block(call)
+}
+
+// checkStatements reports the statements that don't parse.
+func checkStatements(what, statements string) error {
+	src := "package p\nfunc _() {\n" + statements + "\n}\n"
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return nil
+}
+
+// qualifiedCode renders the statements as they are, except for the
+// selectors of the imported package names, such as slog.String, which are
+// qualified so that the package is imported by the generated file. The
+// names of the variables in scope, along with those declared by the
+// statements through :=, shadow the packages.
+func qualifiedCode(statements string, imports map[string]string, variables []string) jen.Code {
+	type scanned struct {
+		offset int
+		tok    token.Token
+		lit    string
+	}
+	statements = strings.TrimSpace(statements)
+	src := []byte(statements)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	var tokens []scanned
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		tokens = append(tokens, scanned{offset: file.Offset(pos), tok: tok, lit: lit})
+	}
+	shadowed := make(map[string]bool, len(variables))
+	for _, variable := range variables {
+		shadowed[variable] = true
+	}
+	for i, t := range tokens {
+		if t.tok != token.DEFINE {
+			continue
+		}
+		for j := i - 1; j >= 0 && (tokens[j].tok == token.IDENT || tokens[j].tok == token.COMMA); j-- {
+			if tokens[j].tok == token.IDENT {
+				shadowed[tokens[j].lit] = true
+			}
+		}
+	}
+
+	code := jen.Null()
+	last := 0
+	for i := 0; i+2 < len(tokens); i++ {
+		name, dot, sel := tokens[i], tokens[i+1], tokens[i+2]
+		path, imported := imports[name.lit]
+		if name.tok != token.IDENT || !imported || shadowed[name.lit] || dot.tok != token.PERIOD || sel.tok != token.IDENT {
+			continue
+		}
+		if i > 0 && tokens[i-1].tok == token.PERIOD {
+			continue
+		}
+		if raw := statements[last:name.offset]; raw != "" {
+			code.Op(raw)
+		}
+		code.Qual(path, sel.lit)
+		last = sel.offset + len(sel.lit)
+		i += 2
+	}
+	if raw := statements[last:]; raw != "" {
+		code.Op(raw)
+	}
+	return code
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dave/jennifer/jen"
+)
+
+func TestQualifiedCode(t *testing.T) {
+	// l is imported as logging, so that a qualified selector is renamed
+	imports := map[string]string{"l": "example.com/logging"}
+	tests := []struct {
+		name       string
+		statements string
+		variables  []string
+		want       string
+	}{
+		{
+			name:       "selector",
+			statements: `l.Info("called")`,
+			want:       `logging.Info("called")`,
+		},
+		{
+			name:       "selectors of several statements",
+			statements: "start := l.Now()\nl.Since(start)",
+			want:       "start := logging.Now()\nlogging.Since(start)",
+		},
+		{
+			name:       "shadowed by a parameter",
+			statements: `l.Info("called")`,
+			variables:  []string{"l"},
+			want:       `l.Info("called")`,
+		},
+		{
+			name:       "shadowed by a local variable",
+			statements: "a, l := s.Logger()\nl.Info(a)",
+			want:       "a, l := s.Logger()\nl.Info(a)",
+		},
+		{
+			name:       "field of the same name",
+			statements: `s.l.Info("called")`,
+			want:       `s.l.Info("called")`,
+		},
+		{
+			name:       "not imported",
+			statements: `fmt.Println("called")`,
+			want:       `fmt.Println("called")`,
+		},
+		{
+			name:       "comment",
+			statements: "// l.Info logs\nl.Info(\"called\")",
+			want:       "// l.Info logs\nlogging.Info(\"called\")",
+		},
+		{
+			name:       "string",
+			statements: "l.Info(\"l.Info\", `l.Debug`)",
+			want:       "logging.Info(\"l.Info\", `l.Debug`)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := qualifiedCode(test.statements, imports, test.variables)
+			got := fmt.Sprintf("%#v", jen.Func().Id("_").Params().Block(code))
+			got = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(got, "func _() {"), "}"))
+			got = strings.ReplaceAll(got, "\n\t", "\n")
+			if got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestImportPaths(t *testing.T) {
+	paths, err := importPaths(
+		map[string]string{"example.com/api/v1": "v1", "example.com/store/v1": "v12"},
+		map[string]string{"log/slog": "slog", "example.com/api/v1": "v1"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"v1": "example.com/api/v1", "v12": "example.com/store/v1", "slog": "log/slog"}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+
+	_, err = importPaths(
+		map[string]string{"example.com/api/v1": "v1"},
+		map[string]string{"example.com/store/v1": "v1"},
+	)
+	if err == nil || err.Error() != "imports: v1 names both example.com/api/v1 and example.com/store/v1" {
+		t.Errorf("got %v, want a conflict of v1", err)
+	}
+}
+
+func TestExternalPrepareCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the plugin is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), externalPrefix+"hang")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 60\n"), 0o700); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	g := &Generator{pkgPath: "example.com/service", pkgName: "service", ctx: ctx}
+
+	start := time.Now()
+	_, err := external{name: "hang", path: path}.prepare(g, &ServiceModel{TypeName: "Service"})
+	if !goerrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the deadline to be exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("the plugin ran for %s", elapsed)
+	}
+}
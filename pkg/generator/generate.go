@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+
+	"github.com/gabizou/middleware-generator/pkg/errors"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Options describe the middleware to Generate, as the flags of
+// cmd/generator do.
+type Options struct {
+	// Dir is the directory of the package declaring the types, defaulting
+	// to the current directory.
+	Dir string
+	// Types are the interfaces to generate middleware for, which may be
+	// qualified references or globs, see InterpretAll.
+	Types []string
+	// Patterns are the packages to discover the interfaces annotated by
+	// //middleware:generate in instead of the Types, such as ./..., along
+	// with the plugins of each.
+	Patterns []string
+	// Middlewares are the middleware types paired with each of the Types,
+	// inferred or declared when empty.
+	Middlewares []string
+	// Plugins are the customizers generating the middleware, each of them
+	// as name[:key=value,...].
+	Plugins []string
+	// Combine puts the middleware of all Plugins in a single file.
+	Combine bool
+	// Output overrides the name of the generated file, or the directory
+	// generated into, see File.Output.
+	Output string
+	// Header is an additional comment for the header of the generated files.
+	Header string
+	// Args are recorded in the header of the generated files.
+	Args []string
+	// PackageName is the name the package of the Dir is expected to have.
+	PackageName string
+}
+
+// GeneratedFile is the rendered content of a file along with its path.
+type GeneratedFile struct {
+	Path    string
+	Content []byte
+}
+
+// Write saves the file.
+func (f GeneratedFile) Write() error {
+	return os.WriteFile(f.Path, f.Content, 0o644) //nolint:gosec
+}
+
+// Diff compares the file with the one on disk, returning a unified diff
+// when it is stale or missing, and nothing when it is up to date.
+func (f GeneratedFile) Diff() (string, error) {
+	existing, err := os.ReadFile(f.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if bytes.Equal(existing, f.Content) {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(string(f.Content)),
+		FromFile: f.Path,
+		ToFile:   f.Path + " (generated)",
+		Context:  3,
+	})
+}
+
+// Generate renders the middleware described by the Options without writing
+// anything, loading every package only once. Problems are reported as the
+// errors of package errors, while cancelling the context stops the loading
+// of packages and kills the external plugins still running.
+func Generate(ctx context.Context, opts Options) ([]GeneratedFile, error) {
+	if len(opts.Patterns) > 0 {
+		if len(opts.Types) > 0 || len(opts.Middlewares) > 0 || len(opts.Plugins) > 0 || opts.Output != "" {
+			return nil, errors.InvalidOptionsErr{
+				Reason: "the types, middlewares, plugins and output of discovered interfaces are those of their annotations",
+			}
+		}
+		files, caches, err := opts.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return caches.generate(files)
+	}
+	files, err := opts.files()
+	if err != nil {
+		return nil, err
+	}
+	return generateFiles(ctx, files)
+}
+
+func generateFiles(ctx context.Context, files []*File) ([]GeneratedFile, error) {
+	return newPackageCaches(ctx).generate(files)
+}
+
+// generate interprets the Files, rendering the files of all of them.
+func (c *packageCaches) generate(files []*File) ([]GeneratedFile, error) {
+	generators, err := c.interpretAll(files)
+	if err != nil {
+		return nil, err
+	}
+	var generated []GeneratedFile
+	for _, g := range generators {
+		rendered, err := g.Files()
+		if err != nil {
+			return nil, err
+		}
+		generated = append(generated, rendered...)
+	}
+	return generated, nil
+}
+
+// files creates a File for each of the Types, validating the Options.
+func (o Options) files() ([]*File, error) {
+	if len(o.Types) == 0 || len(o.Plugins) == 0 {
+		return nil, errors.InvalidOptionsErr{Reason: "a type and at least one plugin are required"}
+	}
+	middlewares := o.Middlewares
+	if len(middlewares) == 0 {
+		middlewares = make([]string, len(o.Types))
+	}
+	if len(middlewares) != len(o.Types) {
+		return nil, errors.InvalidOptionsErr{Reason: "a middleware must be named for each type"}
+	}
+	dir := o.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if o.Output != "" {
+		if _, name := splitOutput(dir, o.Output); name != "" &&
+			(len(o.Plugins) > 1 && !o.Combine || len(o.Types) > 1 || strings.ContainsAny(o.Types[0], "*?[")) {
+			return nil, errors.InvalidOptionsErr{
+				Reason: "an output file is only supported for a single type and either a single plugin or combined output, unless it is a directory",
+			}
+		}
+	}
+	files := make([]*File, len(o.Types))
+	for i, typeName := range o.Types {
+		files[i] = &File{
+			Directory:   dir,
+			TypeName:    strings.TrimSpace(typeName),
+			Middleware:  strings.TrimSpace(middlewares[i]),
+			Customizers: o.Plugins,
+			Combine:     o.Combine,
+			Args:        o.Args,
+			Header:      o.Header,
+			Output:      o.Output,
+			PackageName: o.PackageName,
+			PluginDir:   dir,
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,221 @@
+package generator_test
+
+import (
+	"context"
+	goerrors "errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gabizou/middleware-generator/pkg/errors"
+	"github.com/gabizou/middleware-generator/pkg/generator"
+	_ "github.com/gabizou/middleware-generator/pkg/plugins/logging"
+	_ "github.com/gabizou/middleware-generator/pkg/plugins/tracing"
+)
+
+// The packages of testdata import nothing, as the standard library may be
+// newer than the type checker loading it from source.
+const (
+	servicePath = "github.com/gabizou/middleware-generator/pkg/generator/testdata/service"
+	storePath   = "github.com/gabizou/middleware-generator/pkg/generator/testdata/store"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/golden")
+
+func TestGenerateGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    generator.Options
+		files   []string
+		methods []string
+		// redacted are the parameters that must not be recorded
+		redacted []string
+	}{
+		{
+			name:    "service",
+			opts:    generator.Options{Dir: "testdata/service", Types: []string{"Service"}, Plugins: []string{"tracer", "logger"}},
+			files:   []string{"middleware_service.go", "tracer_service.go", "logger_service.go"},
+			methods: []string{"Find", "Login", "Names", "Ping"},
+		},
+		{
+			name:    "embedded interfaces",
+			opts:    generator.Options{Dir: "testdata/conn", Types: []string{"Conn"}, Plugins: []string{"logger"}},
+			files:   []string{"middleware_conn.go", "logger_conn.go"},
+			methods: []string{"Close", "Flush", "Send"},
+		},
+		{
+			name:     "redacted parameters",
+			opts:     generator.Options{Dir: "testdata/account", Types: []string{"Account"}, Plugins: []string{"logger"}},
+			files:    []string{"middleware_account.go", "logger_account.go"},
+			methods:  []string{"Rename", "Reset", "SignIn"},
+			redacted: []string{"password", "token", "pin"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Args = []string{"-type", test.opts.Types[0]}
+			for _, plugin := range test.opts.Plugins {
+				test.opts.Args = append(test.opts.Args, "-plugin", plugin)
+			}
+			files, err := generator.Generate(context.Background(), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, file := range files {
+				name := filepath.Base(file.Path)
+				names = append(names, name)
+				golden := filepath.Join("testdata", "golden", name+".golden")
+				if *update {
+					if err := os.WriteFile(golden, file.Content, 0o644); err != nil { //nolint:gosec
+						t.Fatal(err)
+					}
+				} else if want, err := os.ReadFile(golden); err != nil {
+					t.Fatal(err)
+				} else if string(file.Content) != string(want) {
+					t.Errorf("%s differs from %s:\n%s", name, golden, file.Content)
+				}
+				if strings.HasPrefix(name, "middleware_") {
+					continue
+				}
+				if got := methodNames(t, file.Content); !reflect.DeepEqual(got, test.methods) {
+					t.Errorf("%s implements %v, want %v", name, got, test.methods)
+				}
+				for _, param := range test.redacted {
+					if recorded := fmt.Sprintf("%q, %s)", param, param); strings.Contains(string(file.Content), recorded) {
+						t.Errorf("%s records the redacted %s", name, param)
+					}
+				}
+			}
+			if !reflect.DeepEqual(names, test.files) {
+				t.Errorf("generated %v, want %v", names, test.files)
+			}
+		})
+	}
+}
+
+// methodNames lists the methods declared by the generated file.
+func methodNames(t *testing.T, content []byte) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "", content, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil {
+			names = append(names, fn.Name.Name)
+		}
+	}
+	return names
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts generator.Options
+		// check asserts the type of the error, returning its message
+		check func(t *testing.T, err error) string
+		want  string
+	}{
+		{
+			name: "not an interface",
+			opts: generator.Options{Types: []string{"Thing"}, Plugins: []string{"tracer"}},
+			check: func(t *testing.T, err error) string {
+				var notInterface errors.NotAnInterfaceErr
+				if !goerrors.As(err, &notInterface) {
+					t.Fatalf("got %T, want NotAnInterfaceErr", err)
+				}
+				return notInterface.Error()
+			},
+			want: "testdata/service/service.go:19:6: Thing is not an interface (it is a struct)",
+		},
+		{
+			name: "unexported types of another package",
+			opts: generator.Options{Types: []string{storePath + ".Users"}, Plugins: []string{"tracer"}},
+			check: func(t *testing.T, err error) string {
+				list, ok := err.(errors.List)
+				if !ok || len(list) != 2 {
+					t.Fatalf("got %T %v, want a List of 2", err, err)
+				}
+				for _, problem := range list {
+					if _, ok := problem.(errors.UnexportedTypeErr); !ok {
+						t.Errorf("got %T, want UnexportedTypeErr", problem)
+					}
+				}
+				return list.Error()
+			},
+			want: "testdata/store/store.go:12:2: user is not exported by package " + storePath + "\n" +
+				"testdata/store/store.go:13:2: role is not exported by package " + storePath,
+		},
+		{
+			name: "unexported types along with a bad middleware",
+			opts: generator.Options{
+				Types:       []string{storePath + ".Roles"},
+				Middlewares: []string{storePath + ".RolesMiddleware"},
+				Plugins:     []string{"tracer"},
+			},
+			check: func(t *testing.T, err error) string {
+				list, ok := err.(errors.List)
+				if !ok || len(list) != 2 {
+					t.Fatalf("got %T %v, want a List of 2", err, err)
+				}
+				if _, ok := list[0].(errors.UnexportedTypeErr); !ok {
+					t.Errorf("got %T, want UnexportedTypeErr", list[0])
+				}
+				if _, ok := list[1].(errors.BadMiddlewareTypeErr); !ok {
+					t.Errorf("got %T, want BadMiddlewareTypeErr", list[1])
+				}
+				return list.Error()
+			},
+			want: "testdata/store/store.go:18:2: role is not exported by package " + storePath + "\n" +
+				"testdata/store/store.go:21:6: RolesMiddleware must be declared as func(Roles) Roles (it is func(Roles) error)",
+		},
+		{
+			name: "misspelled type",
+			opts: generator.Options{Types: []string{"Servce"}, Plugins: []string{"tracer"}},
+			check: func(t *testing.T, err error) string {
+				var notFound errors.TypeNotFoundErr
+				if !goerrors.As(err, &notFound) {
+					t.Fatalf("got %T, want TypeNotFoundErr", err)
+				}
+				if notFound.Suggestion != "Service" {
+					t.Errorf("suggested %q, want Service", notFound.Suggestion)
+				}
+				return notFound.Error()
+			},
+			want: "Servce not found in declared types of " + servicePath + "; did you mean Service?",
+		},
+		{
+			name: "missing plugin",
+			opts: generator.Options{Types: []string{"Service"}},
+			check: func(t *testing.T, err error) string {
+				var invalid errors.InvalidOptionsErr
+				if !goerrors.As(err, &invalid) {
+					t.Fatalf("got %T, want InvalidOptionsErr", err)
+				}
+				return invalid.Error()
+			},
+			want: "a type and at least one plugin are required",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Dir = "testdata/service"
+			_, err := generator.Generate(context.Background(), test.opts)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := test.check(t, err); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -1,137 +1,288 @@
 package generator
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"log"
+	"path/filepath"
 	"strings"
 
+	"github.com/gabizou/middleware-generator/pkg/errors"
 	"github.com/gabizou/middleware-generator/pkg/interpreter"
 
 	"github.com/dave/jennifer/jen"
-
-	"golang.org/x/tools/go/packages"
 )
 
 // Generator is an object to take an interpreted interpreter.ServiceModel and
 // using a Customizer, generates the middleware output.
 type Generator struct {
 	f                    *jen.File // The generating file we're working on
+	pkgPath              string
+	pkgName              string
+	command              string
+	headers              []string
+	dir                  string
+	output               string
 	ourType              string
 	ourPtr               rune
 	svcPtr               string
 	service              *ServiceModel
 	interpretedFunctions []interpreter.DeclaredFunction
-	customizer           Customizer
-}
-
-const (
-	_packageParsing = packages.NeedTypes |
-		packages.NeedTypesSizes |
-		packages.NeedImports |
-		packages.NeedName |
-		packages.NeedFiles |
-		packages.NeedCompiledGoFiles |
-		packages.NeedTypesInfo |
-		packages.NeedSyntax
-)
+	customizer           Customizer // The customizer generating into f
+	customizerName       string
+	customizers          []Customizer
+	customizerNames      []string
+	combine              bool
+	outputs              []outputFile
+	pluginDir            string
+	ctx                  context.Context // cancels the external plugins run by AddModel
+}
 
-func (g *Generator) parsePackage(file *File) {
-	cfg := &packages.Config{
-		Mode:       _packageParsing,
-		Tests:      false,
-		BuildFlags: []string{},
+// context is that of the generation, cancelling the external plugins.
+func (g *Generator) context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
 	}
+	return g.ctx
+}
 
-	pkgs, err := packages.Load(cfg, file.Directory)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if len(pkgs) != 1 {
-		log.Fatalf("error: %d packages found", len(pkgs))
+// outputFile is a generated file along with the name it is saved as.
+type outputFile struct {
+	name string
+	f    *jen.File
+}
+
+// usePackage generates into the already loaded package of the File.
+func (g *Generator) usePackage(file *File) {
+	g.pkgPath, g.pkgName = file.Package.PkgPath, file.Package.Name
+	g.dir = packageDir(file.Package, file.Directory)
+}
+
+// newFile creates another file to generate into the same package,
+// carrying the headers already added.
+func (g *Generator) newFile() *jen.File {
+	return g.newFileGeneratedBy(fmt.Sprintf("\"middleware-generator %s\"", g.command))
+}
+
+// newSharedFile creates a file which is generated alike by every
+// customizer, so it doesn't record the command of any one of them.
+func (g *Generator) newSharedFile() *jen.File {
+	return g.newFileGeneratedBy("middleware-generator")
+}
+
+func (g *Generator) newFileGeneratedBy(command string) *jen.File {
+	f := jen.NewFilePathName(g.pkgPath, g.pkgName)
+	f.HeaderComment(fmt.Sprintf("Code generated by %s; DO NOT EDIT.", command))
+	for _, header := range g.headers {
+		f.HeaderComment(header)
 	}
-	g.f = jen.NewFile(pkgs[0].Name)
+	return f
 }
 
+// AddFileHeader records the command generating the files in their header.
 func (g *Generator) AddFileHeader(header string) {
-	g.f.HeaderComment(fmt.Sprintf("Code generated by \"middleware-generator %s\"; DO NOT EDIT.", header))
+	g.command = header
 }
 
-func (g *Generator) AddModel(model *ServiceModel) {
-	pointerName := string(strings.ToLower(model.TypeName)[0])
-	// Specify the default import name because we don't want to alias this
-	for alias, v := range g.customizer.GetRequiredImportNames() {
-		g.f.ImportName(alias, v)
+// AddHeaderComment adds a comment, such as a copyright notice, above
+// the package clause of the generated files.
+func (g *Generator) AddHeaderComment(comment string) {
+	g.headers = append(g.headers, comment)
+}
+
+// AddModel generates the middleware of each customizer for the model, every
+// one into a file of its own unless combined, see SetCombined. The struct of
+// each is named by its ServiceModel.StructPrefix and the full type name, so
+// that wrappers of several types and customizers can share a package.
+//
+// The middleware type, when missing, and the helpers chaining middlewares
+// are generated into middleware_${ServiceModel.TypeName}.go.
+func (g *Generator) AddModel(model *ServiceModel) error {
+	pointerName := receiverName(model.TypeName)
+	typeName := strings.ToLower(model.TypeName)
+	var combined *jen.File
+	if g.combine {
+		combined = g.newFile()
+		g.addOutput(g.outputOr(middlewareFileName(model.TypeName)), combined)
+	}
+	shared := combined
+	if shared == nil {
+		shared = g.newSharedFile()
+		g.addOutput(middlewareFileName(model.TypeName), shared)
+	}
+	if model.DeclareMiddleware {
+		g.genMiddlewareType(shared, model)
+	}
+	g.genChain(shared, model)
+
+	structNames := make(map[string]bool, len(g.customizers))
+	for c, customizer := range g.customizers {
+		if preparing, ok := customizer.(preparedCustomizer); ok {
+			prepared, err := preparing.prepare(g, model)
+			if err != nil {
+				return err
+			}
+			customizer = prepared
+		}
+		g.customizer, g.customizerName = customizer, g.customizerNames[c]
+		g.f = combined
+		if g.f == nil {
+			g.f = g.newFile()
+			g.addOutput(g.outputOr(fmt.Sprintf("%s_%s.go", customizer.FileNamePrefix(), typeName)), g.f)
+		}
+		// Specify the default import name because we don't want to alias this
+		for alias, v := range customizer.GetRequiredImportNames() {
+			g.f.ImportName(alias, v)
+		}
+		// each customizer configures a model of its own
+		configured := *model
+		customizer.ConfigureModel(&configured)
+
+		middlewareTypeName := fmt.Sprintf(configured.StructPrefix, model.TypeName)
+		if structNames[middlewareTypeName] {
+			return errors.DuplicateMiddlewareErr{Struct: middlewareTypeName, Type: model.TypeName}
+		}
+		structNames[middlewareTypeName] = true
+		g.ourType = middlewareTypeName
+		g.ourPtr = []rune(receiverName(middlewareTypeName))[0]
+		configured.StructPtr = string(g.ourPtr)
+		configured.ServicePtr = pointerName
+		g.svcPtr = pointerName
+		g.service = &configured
+		g.interpretedFunctions = configured.Interface
+		g.genFactoryMethod(&configured)
+		g.genStruct(&configured)
+		g.genInterfaceMethods()
 	}
-	g.customizer.ConfigureModel(model)
+	return nil
+}
+
+// receiverName names the receiver of a type by its lowercased first letter.
+func receiverName(typeName string) string {
+	return string([]rune(strings.ToLower(typeName))[:1])
+}
+
+// genMiddlewareType creates the following:
+//
+//	type ${ServiceModel.Middleware}${ServiceModel.TypeParams} func(${ServiceModel.TypeName}) ${ServiceModel.TypeName}
+func (g *Generator) genMiddlewareType(f *jen.File, model *ServiceModel) *jen.Statement {
+	return f.Type().
+		Id(model.Middleware).
+		Add(model.TypeParamList()).
+		Func().
+		Params(model.ServiceType()).
+		Add(model.ServiceType())
+}
 
-	middlewareTypeName := fmt.Sprintf(model.StructPrefix, string(model.TypeName[0]))
-	g.ourType = middlewareTypeName
-	g.ourPtr = []rune(strings.ToLower(middlewareTypeName))[0]
-	model.StructPtr = string(g.ourPtr)
-	model.ServicePtr = pointerName
-	g.svcPtr = pointerName
-	g.service = model
-	g.interpretedFunctions = model.Interface
-	g.genFactoryMethod(model)
-	g.genStruct(model)
-	g.genInterfaceMethods()
+// genChain creates the following:
+//
+//	func Chain${ServiceModel.TypeName}(mws ...${ServiceModel.Middleware}) ${ServiceModel.Middleware} {
+//	  return func(svc ${ServiceModel.TypeName}) ${ServiceModel.TypeName} {
+//	    for i := len(mws) - 1; i >= 0; i-- {
+//	      svc = mws[i](svc)
+//	    }
+//	    return svc
+//	  }
+//	}
+//
+//	func Wrap${ServiceModel.TypeName}(svc ${ServiceModel.TypeName}, mws ...${ServiceModel.Middleware}) ${ServiceModel.TypeName} {
+//	  return Chain${ServiceModel.TypeName}(mws...)(svc)
+//	}
+func (g *Generator) genChain(f *jen.File, model *ServiceModel) {
+	chain := "Chain" + model.TypeName
+	wrap := "Wrap" + model.TypeName
+	f.Commentf("%s composes the middlewares into one, the first of them being the outermost", chain)
+	f.Comment("wrapper, which is called first.")
+	f.Func().
+		Id(chain).
+		Add(model.TypeParamList()).
+		Params(jen.Id("mws").Op("...").Add(model.MiddlewareType())).
+		Add(model.MiddlewareType()).
+		Block(
+			jen.Return(jen.Func().
+				Params(jen.Id("svc").Add(model.ServiceType())).
+				Add(model.ServiceType()).
+				Block(
+					jen.For(
+						jen.Id("i").Op(":=").Len(jen.Id("mws")).Op("-").Lit(1),
+						jen.Id("i").Op(">=").Lit(0),
+						jen.Id("i").Op("--"),
+					).Block(
+						jen.Id("svc").Op("=").Id("mws").Index(jen.Id("i")).Call(jen.Id("svc")),
+					),
+					jen.Return(jen.Id("svc")),
+				)),
+		)
+	f.Commentf("%s wraps the service with the middlewares, see %s.", wrap, chain)
+	f.Func().
+		Id(wrap).
+		Add(model.TypeParamList()).
+		Params(
+			jen.Id("svc").Add(model.ServiceType()),
+			jen.Id("mws").Op("...").Add(model.MiddlewareType()),
+		).
+		Add(model.ServiceType()).
+		Block(
+			jen.Return(model.Instantiate(chain).Call(jen.Id("mws").Op("...")).Call(jen.Id("svc"))),
+		)
 }
 
 // genStruct creates the following:
-// type logger${shortenedName} struct {
-//   l log.Logger
-//   ${shortenedName} ${ServiceModel.TypeName}
-// }
+//
+//	type logger${ServiceModel.TypeName} struct {
+//	  lg *slog.Logger
+//	  ${shortenedName} ${ServiceModel.TypeName}
+//	}
 func (g *Generator) genStruct(model *ServiceModel) *jen.Statement {
-	fields := make([]jen.Code, len(model.InputParameters))
-	for i, parameter := range model.InputParameters {
-		field := jen.Id(parameter.FieldName)
-		if parameter.TypePath != "" {
-			field.Qual(parameter.TypePath, parameter.TypeName)
-		} else {
-			field.Id(parameter.TypeName)
-		}
-		fields[i] = field
+	var fields []jen.Code
+	for _, parameter := range model.structFields() {
+		fields = append(fields, jen.Id(parameter.FieldName).Add(parameter.typeCode()))
 	}
-	fields = append(fields, jen.Id(g.svcPtr).Id(model.TypeName))
+	fields = append(fields, jen.Id(g.svcPtr).Add(model.ServiceType()))
 
 	return g.f.Type().
 		Id(g.ourType).
+		Add(model.TypeParamList()).
 		Struct(
 			fields...,
 		)
 }
 
 // genFactoryMethod creates the following:
-//  func New${ServiceModel.TypeName}(${ServiceModel.InputParameters}.Name ${ServiceModel.InputParameters}) ${ServiceModel.Middleware} {
-//    return func($shortenedName ${ServiceModel.TypeName}) ${ServiceModel.TypeName} {
-//      return &${ServiceModel.${shortenedName}{
-//          fields: variables,
-//          ${shortenedName}: ${$shortenedName},
-//    }
-//  }
-//}
+//
+//	 func New${ServiceModel.TypeName}(${ServiceModel.InputParameters}.Name ${ServiceModel.InputParameters}) ${ServiceModel.Middleware} {
+//	   return func($shortenedName ${ServiceModel.TypeName}) ${ServiceModel.TypeName} {
+//	     return &${ServiceModel.${shortenedName}{
+//	         fields: variables,
+//	         ${shortenedName}: ${$shortenedName},
+//	   }
+//	 }
+//	}
 func (g *Generator) genFactoryMethod(model *ServiceModel) *jen.Statement {
 	genParams := make([]jen.Code, len(model.InputParameters))
 	for i, parameter := range model.InputParameters {
-		genParams[i] = jen.Id(parameter.VariableName).Qual(parameter.TypePath, parameter.TypeName)
+		genParams[i] = jen.Id(parameter.VariableName).Add(parameter.typeCode())
 	}
 	return g.f.Func().
 		Id(fmt.Sprintf("New%s%s", model.TypeName, g.customizer.FactorySuffix())).
+		Add(model.TypeParamList()).
 		Params(genParams...).
-		Id(model.Middleware).
+		Add(model.MiddlewareType()).
 		BlockFunc(func(gr *jen.Group) {
+			if factory, ok := g.customizer.(FactoryCustomizer); ok {
+				factory.GenerateFactoryImplementation(gr, model)
+			}
 			gr.ReturnFunc(func(ig *jen.Group) {
 				ig.Func().
-					Params(jen.Id(g.svcPtr).Id(model.TypeName)).
-					Id(model.TypeName).
+					Params(jen.Id(g.svcPtr).Add(model.ServiceType())).
+					Add(model.ServiceType()).
 					BlockFunc(func(ng *jen.Group) {
 						fieldSetters := make(jen.Dict)
 						fieldSetters[jen.Id(g.svcPtr)] = jen.Id(g.svcPtr)
-						for _, parameter := range model.InputParameters {
+						for _, parameter := range model.structFields() {
 							fieldSetters[jen.Id(parameter.FieldName)] = jen.Id(parameter.VariableName)
 						}
-						ng.Return(jen.Op("&").Id(g.ourType).Values(fieldSetters))
+						ng.Return(jen.Op("&").Add(model.Instantiate(g.ourType)).Values(fieldSetters))
 					})
 			})
 		})
@@ -147,7 +298,7 @@ func (g *Generator) genInterfaceMethods() {
 
 func (g *Generator) genFunctionDeclaration(method interpreter.DeclaredFunction) jen.Code {
 	genedFunction := jen.Func().
-		Params(jen.Id(g.service.StructPtr).Op("*").Id(g.ourType))
+		Params(jen.Id(g.service.StructPtr).Op("*").Add(g.service.Instantiate(g.ourType)))
 	genedFunction = genedFunction.
 		Id(method.FunctionName())
 	var genParams []jen.Code
@@ -158,13 +309,83 @@ func (g *Generator) genFunctionDeclaration(method interpreter.DeclaredFunction)
 	genedFunction.Params(genParams...)
 
 	genedFunction.Add(method.ReturnDefinition())
+	if method.Directives().Skips(g.customizerName) {
+		/* code to generate
+		return ${service.StructPtr}.${service.ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+		*/
+		call := g.service.ForwardCall(method)
+		if len(method.Returns()) > 0 {
+			call = jen.Return(call)
+		}
+		genedFunction.Block(call)
+		return genedFunction
+	}
 	g.customizer.GenerateFunctionImplementation(genedFunction, g.service, method)
 	return genedFunction
 }
 
-func (g *Generator) Print() {
-	err := g.f.Save(fmt.Sprintf("%s_%s.go", g.customizer.FileNamePrefix(), strings.ToLower(g.service.TypeName)))
+// SetOutput overrides the name of the generated file, which otherwise
+// is ${Customizer.FileNamePrefix}_${ServiceModel.TypeName}.go, or
+// middleware_${ServiceModel.TypeName}.go when combined. A relative
+// output is placed in the directory of the package, see File.Output for
+// generating into another directory.
+func (g *Generator) SetOutput(output string) {
+	g.output = output
+}
+
+// SetCombined puts the middleware of every customizer, along with the
+// declaration of a missing middleware type, in a single file.
+func (g *Generator) SetCombined(combine bool) {
+	g.combine = combine
+}
+
+// middlewareFileName is the file declaring the middleware type and its
+// helpers, or every middleware when combined.
+func middlewareFileName(typeName string) string {
+	return fmt.Sprintf("middleware_%s.go", strings.ToLower(typeName))
+}
+
+func (g *Generator) outputOr(name string) string {
+	if g.output != "" {
+		return g.output
+	}
+	return name
+}
+
+func (g *Generator) addOutput(name string, f *jen.File) {
+	g.outputs = append(g.outputs, outputFile{name: name, f: f})
+}
+
+// Print saves the generated files.
+func (g *Generator) Print() error {
+	files, err := g.Files()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	for _, file := range files {
+		if err := file.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Files renders the generated files without saving them.
+func (g *Generator) Files() ([]GeneratedFile, error) {
+	files := make([]GeneratedFile, len(g.outputs))
+	for i, output := range g.outputs {
+		var content bytes.Buffer
+		if err := output.f.Render(&content); err != nil {
+			return nil, err
+		}
+		files[i] = GeneratedFile{Path: g.outputPath(output), Content: content.Bytes()}
+	}
+	return files, nil
+}
+
+func (g *Generator) outputPath(output outputFile) string {
+	if filepath.IsAbs(output.name) {
+		return output.name
 	}
+	return filepath.Join(g.dir, output.name)
 }
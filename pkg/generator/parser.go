@@ -1,110 +1,753 @@
 package generator
 
 import (
+	"context"
 	"fmt"
+	"go/token"
 	"go/types"
 	"os"
+	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/gabizou/middleware-generator/pkg/errors"
 	"github.com/gabizou/middleware-generator/pkg/interpreter"
 
+	"github.com/dave/jennifer/jen"
+
 	"golang.org/x/tools/go/packages"
 )
 
 type ServiceModel struct {
-	TypeName        string
-	Middleware      string
+	TypeName string
+	// TypePath is the import path of the package declaring the interface
+	// when it is not the package being generated into.
+	TypePath   string
+	Middleware string
+	// MiddlewarePath is the import path of the package declaring the
+	// Middleware when it is not the package being generated into.
+	MiddlewarePath  string
 	StructPrefix    string
 	Interface       []interpreter.DeclaredFunction
 	InputParameters []MiddlewareParameter
-	StructPtr       string
-	ServicePtr      string
+	// StructFields are additional fields of the generated struct, assigned
+	// from the local variables declared by a FactoryCustomizer.
+	StructFields []MiddlewareParameter
+	StructPtr    string
+	ServicePtr   string
+	// TypeParams are declared when the interface is generic, which are
+	// shared by the Middleware type and the generated struct.
+	TypeParams []interpreter.TypeParameter
+	// DeclareMiddleware is set when the Middleware type isn't declared yet
+	// and has to be generated as func(${TypeName}) ${TypeName}.
+	DeclareMiddleware bool
+}
+
+// TypeParamList renders the type parameters of a generic service as
+// declared on a type or function, e.g. [T any], or nothing otherwise.
+func (s *ServiceModel) TypeParamList() jen.Code {
+	if len(s.TypeParams) == 0 {
+		return jen.Null()
+	}
+	decls := make([]jen.Code, len(s.TypeParams))
+	for i, param := range s.TypeParams {
+		decls[i] = jen.Id(param.Name()).Add(param.Constraint())
+	}
+	return jen.Types(decls...)
+}
+
+// ServiceType renders the interface type, e.g. store.Repository[T].
+func (s *ServiceModel) ServiceType() *jen.Statement {
+	return s.instantiate(qualify(s.TypePath, s.TypeName))
+}
+
+// MiddlewareType renders the middleware type, e.g. RepoMiddleware[T].
+func (s *ServiceModel) MiddlewareType() *jen.Statement {
+	return s.instantiate(qualify(s.MiddlewarePath, s.Middleware))
+}
+
+// Instantiate renders the given type name of the package with the type
+// parameters of a generic service as its type arguments, e.g. Store[T].
+func (s *ServiceModel) Instantiate(name string) *jen.Statement {
+	return s.instantiate(jen.Id(name))
+}
+
+func (s *ServiceModel) instantiate(code *jen.Statement) *jen.Statement {
+	if len(s.TypeParams) == 0 {
+		return code
+	}
+	args := make([]jen.Code, len(s.TypeParams))
+	for i, param := range s.TypeParams {
+		args[i] = param.AsReturnType()
+	}
+	return code.Types(args...)
+}
+
+func qualify(path, name string) *jen.Statement {
+	if path == "" {
+		return jen.Id(name)
+	}
+	return jen.Qual(path, name)
+}
+
+// structFields lists every InputParameters stored on the generated struct
+// followed by the StructFields.
+func (s *ServiceModel) structFields() []MiddlewareParameter {
+	var fields []MiddlewareParameter
+	for _, parameter := range s.InputParameters {
+		if parameter.FieldName != "" {
+			fields = append(fields, parameter)
+		}
+	}
+	return append(fields, s.StructFields...)
 }
 
 type File struct {
-	Directory  string
-	TypeName   string // Name of the constant type.
+	Directory string
+	TypeName  string // Name of the constant type.
+	// TypePath is the import path of the package declaring TypeName, if
+	// it was given as a qualified reference such as io.ReadWriter.
+	TypePath   string
 	Package    *packages.Package
 	Middleware string
-	Customizer string
+	// MiddlewarePath is the import path of the package declaring
+	// the Middleware, if it was given as a qualified reference.
+	MiddlewarePath string
+	// Customizers generate the middleware, each of them as
+	// name[:key=value,...].
+	Customizers []string
+	// Combine puts the middleware of all Customizers in a single file.
+	Combine bool
+	// Args are recorded in the header of the generated file.
+	Args []string
+	// Header is an additional comment for the header of the generated file.
+	Header string
+	// Output overrides the name of the generated file, relative to the
+	// Directory. It is the directory to generate into instead when it ends
+	// with a separator or is an existing directory, which may belong to
+	// another package than that of the Directory.
+	Output string
+	// PackageName is the name the package of the Directory is expected to
+	// have, such as $GOPACKAGE when run by go generate.
+	PackageName string
+	// PluginDir is the directory the paths in the options of Customizers
+	// are relative to, such as the file of a template, which is otherwise
+	// the working directory.
+	PluginDir string
 }
 
 const (
 	packageLoadingMode = packages.NeedName |
 		packages.NeedTypes |
+		packages.NeedTypesSizes |
 		packages.NeedDeps |
-		packages.NeedSyntax |
+		packages.NeedImports |
+		packages.NeedFiles |
 		packages.NeedSyntax |
 		packages.NeedTypesInfo
 )
 
-func Interpret(dir string, args []string) *Generator {
-	targetFile := &File{
-		Directory:  dir,
-		TypeName:   args[1],
-		Middleware: args[2],
-		Customizer: args[3],
+// Interpret loads the package of the File to generate the middleware of its
+// TypeName, which along with the Middleware may be a qualified reference.
+func Interpret(targetFile *File) (*Generator, error) {
+	generators, err := InterpretAll([]*File{targetFile})
+	if err != nil {
+		return nil, err
+	}
+	return generators[0], nil
+}
+
+// InterpretAll interprets each of the Files, loading every package only once
+// for those sharing a Directory. A File whose TypeName is a glob such as
+// *Repository is expanded to each matching interface of its package.
+// Without a Middleware, the func(X) X type declared for the interface is
+// used, or else ${TypeName}Middleware is declared.
+func InterpretAll(files []*File) ([]*Generator, error) {
+	return interpretAll(context.Background(), files)
+}
+
+func interpretAll(ctx context.Context, files []*File) ([]*Generator, error) {
+	return newPackageCaches(ctx).interpretAll(files)
+}
+
+// interpretAll interprets the Files of each directory in parallel, reporting
+// the problems with every File at once.
+func (c *packageCaches) interpretAll(files []*File) ([]*Generator, error) {
+	var dirs []string
+	byDir := make(map[string][]*File)
+	for _, file := range files {
+		if _, ok := byDir[file.Directory]; !ok {
+			dirs = append(dirs, file.Directory)
+		}
+		byDir[file.Directory] = append(byDir[file.Directory], file)
+	}
+	generators := make([][]*Generator, len(dirs))
+	problems := make([]errors.List, len(dirs))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for d, dir := range dirs {
+		wg.Add(1)
+		go func(d int, dir string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			generators[d], problems[d] = c.interpretDir(byDir[dir])
+		}(d, dir)
+	}
+	wg.Wait()
+
+	var all []*Generator
+	var allProblems errors.List
+	for d := range dirs {
+		all = append(all, generators[d]...)
+		allProblems = append(allProblems, problems[d]...)
+	}
+	if err := allProblems.Err(); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// interpretDir interprets the Files sharing a directory in order.
+func (c *packageCaches) interpretDir(files []*File) ([]*Generator, errors.List) {
+	var generators []*Generator
+	var problems errors.List
+	for _, file := range files {
+		cache := c.of(file.Directory)
+		targetFiles, err := expandFile(file, cache)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		for _, targetFile := range targetFiles {
+			if err := retarget(targetFile, cache); err != nil {
+				problems = append(problems, err)
+				continue
+			}
+			g, err := interpret(targetFile, c.of(targetFile.Directory))
+			if err != nil {
+				problems = append(problems, err)
+				continue
+			}
+			generators = append(generators, g)
+		}
 	}
-	interpretedService, err := parseForService(targetFile)
+	return generators, problems
+}
+
+// retarget resolves the Output of the File against the directory of its
+// package. Should it lie in the directory of another package, the File is
+// moved there with its references to the package of its Directory qualified,
+// as these are no longer declared in the package being generated into.
+func retarget(file *File, cache *packageCache) error {
+	pkg, err := cache.load(".")
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if file.PackageName != "" && pkg.Name != file.PackageName {
+		return errors.PackageNameErr{Dir: file.Directory, Expected: file.PackageName, Found: pkg.Name}
+	}
+	pkgDir := packageDir(pkg, file.Directory)
+	dir, name := splitOutput(pkgDir, file.Output)
+	file.Directory, file.Output = dir, name
+	if dir == pkgDir {
+		return nil
+	}
+	if file.TypePath == "" {
+		file.TypePath = pkg.PkgPath
+	}
+	// a missing middleware type is declared where the wrapper is generated
+	if file.MiddlewarePath == "" && pkg.Types.Scope().Lookup(file.Middleware) != nil {
+		file.MiddlewarePath = pkg.PkgPath
+	}
+	return nil
+}
+
+// packageDir is the directory holding the files of the package, falling
+// back to the directory it was loaded from.
+func packageDir(pkg *packages.Package, dir string) string {
+	if len(pkg.GoFiles) > 0 {
+		return filepath.Dir(pkg.GoFiles[0])
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// splitOutput resolves the output relative to the directory into the
+// directory to generate into and the name of the file, which is empty
+// when the output is a directory.
+func splitOutput(dir, output string) (string, string) {
+	if output == "" {
+		return dir, ""
+	}
+	isDir := strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(filepath.Separator))
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	if info, err := os.Stat(output); isDir || err == nil && info.IsDir() {
+		return filepath.Clean(output), ""
+	}
+	return filepath.Dir(output), filepath.Base(output)
+}
+
+// expandFile splits the qualified references of the File, then creates
+// a File for each interface matching its TypeName when it is a glob. The
+// Middleware of the File is kept when a single interface matches, while it
+// can't be named for several.
+func expandFile(file *File, cache *packageCache) ([]*File, error) {
+	if file.TypePath == "" {
+		file.TypePath, file.TypeName = splitQualified(file.TypeName)
+	}
+	if file.MiddlewarePath == "" {
+		file.MiddlewarePath, file.Middleware = splitQualified(file.Middleware)
+	}
+	if !strings.ContainsAny(file.TypeName, "*?[") {
+		return []*File{file}, nil
+	}
+	pattern := file.TypePath
+	if pattern == "" {
+		pattern = "."
+	}
+	pkg, err := cache.load(pattern)
+	if err != nil {
+		return nil, err
+	}
+	scope := pkg.Types.Scope()
+	var expanded []*File
+	for _, name := range scope.Names() {
+		if matched, _ := path.Match(file.TypeName, name); !matched {
+			continue
+		}
+		obj := scope.Lookup(name)
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok || !iface.IsMethodSet() || !obj.Exported() && file.TypePath != "" {
+			continue
+		}
+		match := *file
+		match.TypeName = name
+		expanded = append(expanded, &match)
+	}
+	if len(expanded) == 0 {
+		return nil, errors.NoMatchingInterfaceErr{Pattern: file.TypeName, Package: pkg.PkgPath}
+	}
+	if len(expanded) > 1 && file.Middleware != "" {
+		matches := make([]string, len(expanded))
+		for i, match := range expanded {
+			matches[i] = match.TypeName
+		}
+		return nil, errors.AmbiguousMiddlewareErr{Middleware: file.Middleware, Pattern: file.TypeName, Matches: matches}
+	}
+	return expanded, nil
+}
+
+func interpret(targetFile *File, cache *packageCache) (*Generator, error) {
+	interpretedService, err := parseForService(targetFile, cache)
+	if err != nil {
+		return nil, err
 	}
 	g := Generator{}
-	g.parsePackage(targetFile)
-	g.AddFileHeader(strings.Join(args[1:], " "))
-	g.SetupCustomizer(targetFile.Customizer)
+	g.usePackage(targetFile)
+	g.AddFileHeader(strings.Join(targetFile.Args, " "))
+	if targetFile.Header != "" {
+		g.AddHeaderComment(targetFile.Header)
+	}
+	g.SetOutput(targetFile.Output)
+	g.SetCombined(targetFile.Combine)
+	g.pluginDir = targetFile.PluginDir
+	g.ctx = cache.ctx
+	if err := g.SetupCustomizer(targetFile.Customizers...); err != nil {
+		return nil, err
+	}
 
 	// Run generate for each type.
-	g.AddModel(interpretedService)
-	return &g
+	if err := g.AddModel(interpretedService); err != nil {
+		return nil, err
+	}
+	return &g, nil
 }
 
-func parseForService(file *File) (*ServiceModel, error) {
+// splitQualified separates a reference such as github.com/acme/store.Repository
+// into its import path and name, where the path of an unqualified name is empty.
+func splitQualified(ref string) (string, string) {
+	dot := strings.LastIndex(ref, ".")
+	if dot < 0 || dot < strings.LastIndex(ref, "/") {
+		return "", ref
+	}
+	return ref[:dot], ref[dot+1:]
+}
+
+func parseForService(file *File, cache *packageCache) (*ServiceModel, error) {
 	// 2. Inspect package and use type checker to infer imported types
-	file.Package = loadPackage(file.Directory)
+	var err error
+	file.Package, err = cache.load(".")
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Package.GoFiles) == 0 {
+		return nil, errors.NoPackageErr{Dir: file.Directory}
+	}
+	declaring := file.Package
+	if file.TypePath == file.Package.PkgPath {
+		file.TypePath = ""
+	}
+	if file.MiddlewarePath == file.Package.PkgPath {
+		file.MiddlewarePath = ""
+	}
+	if file.TypePath != "" {
+		declaring, err = cache.load(file.TypePath)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// 3. Lookup the given source type name in the package declarations
-	obj := file.Package.Types.Scope().Lookup(file.TypeName)
+	scope := declaring.Types.Scope()
+	obj := scope.Lookup(file.TypeName)
 	if obj == nil {
-		failErr(fmt.Errorf("%s not found in declared types of %s",
-			file.TypeName, file.Package))
+		return nil, errors.TypeNotFoundErr{
+			Name:       file.TypeName,
+			Package:    declaring.PkgPath,
+			Suggestion: suggest(scope, file.TypeName, file.TypePath != ""),
+		}
+	}
+	pos := position(declaring, obj.Pos())
+	if file.TypePath != "" && !obj.Exported() {
+		return nil, errors.UnexportedTypeErr{Obj: obj, Pos: pos}
 	}
 
 	// 4. We check if it is a declared type
 	if _, ok := obj.(*types.TypeName); !ok {
-		return nil, errors.UndeclaredTypeErr{Obj: obj}
+		return nil, errors.UndeclaredTypeErr{Obj: obj, Pos: pos}
 	}
 	// 5. We expect the underlying type to be a struct
 	structType, ok := obj.Type().Underlying().(*types.Interface)
 	if !ok {
-		return nil, errors.NotAnInterfaceErr{Obj: obj}
+		return nil, errors.NotAnInterfaceErr{Obj: obj, Pos: pos}
 	}
 
-	// 6. Now we can iterate through fields and access tags
-	iface := interpreter.DeriveInterface(structType)
-	sm := &ServiceModel{Interface: iface, TypeName: file.TypeName, Middleware: file.Middleware}
+	// 6. Embedded interfaces from other packages may bring along methods we can't implement,
+	// while the signatures and constraints may use types we can't refer to
+	var problems errors.List
+	unexported := func(typ types.Type, pos token.Pos) {
+		seen := make(map[*types.TypeName]bool)
+		unexportedTypes(typ, func(obj *types.TypeName) {
+			if !seen[obj] && obj.Pkg().Path() != file.Package.PkgPath {
+				seen[obj] = true
+				problems = append(problems, errors.UnexportedTypeErr{Obj: obj, Pos: position(declaring, pos)})
+			}
+		})
+	}
+	for m := 0; m < structType.NumMethods(); m++ {
+		method := structType.Method(m)
+		if !method.Exported() && method.Pkg() != file.Package.Types {
+			problems = append(problems, errors.UnexportedMethodErr{Func: method, Pos: position(declaring, method.Pos())})
+		}
+		unexported(method.Type(), method.Pos())
+	}
+	if named, ok := obj.Type().(*types.Named); ok {
+		for i := 0; i < named.TypeParams().Len(); i++ {
+			unexported(named.TypeParams().At(i).Constraint(), obj.Pos())
+		}
+	}
+
+	// 7. Now we can iterate through fields and access tags
+	iface, err := interpreter.DeriveInterface(structType, methodDocs(declaring, structType))
+	if bad, ok := err.(errors.BadMethodSignatureTypeErr); ok {
+		bad.Pos = position(declaring, bad.Func.Pos())
+		err = bad
+	}
+	if err != nil {
+		return nil, append(problems, err).Err()
+	}
+	sm := &ServiceModel{
+		Interface:      iface,
+		TypeName:       file.TypeName,
+		TypePath:       file.TypePath,
+		Middleware:     file.Middleware,
+		MiddlewarePath: file.MiddlewarePath,
+	}
+	if named, ok := obj.Type().(*types.Named); ok {
+		sm.TypeParams = interpreter.DeriveTypeParameters(named.TypeParams())
+	}
+
+	// 8. The middleware type is declared for the user when missing, otherwise it has to be func(X) X
+	if file.Middleware == "" {
+		file.Middleware = inferMiddleware(file.Package.Types, obj)
+		sm.Middleware = file.Middleware
+	}
+	middlewarePackage := file.Package
+	if file.MiddlewarePath != "" {
+		middlewarePackage, err = cache.load(file.MiddlewarePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	middlewareScope := middlewarePackage.Types.Scope()
+	middlewareObj := middlewareScope.Lookup(file.Middleware)
+	switch {
+	case middlewareObj == nil && file.MiddlewarePath == "":
+		sm.DeclareMiddleware = true
+	case middlewareObj == nil:
+		problems = append(problems, errors.TypeNotFoundErr{
+			Name:       file.Middleware,
+			Package:    middlewarePackage.PkgPath,
+			Suggestion: suggest(middlewareScope, file.Middleware, true),
+		})
+	default:
+		if err := checkMiddlewareType(obj, middlewareObj); err != nil {
+			bad := err.(errors.BadMiddlewareTypeErr)
+			bad.Pos = position(middlewarePackage, middlewareObj.Pos())
+			problems = append(problems, bad)
+			break
+		}
+		// a previous run declared it in the file that is about to be generated again
+		declaredIn := filepath.Base(file.Package.Fset.Position(middlewareObj.Pos()).Filename)
+		sm.DeclareMiddleware = file.MiddlewarePath == "" &&
+			(declaredIn == middlewareFileName(file.TypeName) || file.Combine && declaredIn == filepath.Base(file.Output))
+	}
+	if err := problems.Err(); err != nil {
+		return nil, err
+	}
 	return sm, nil
 }
 
-func loadPackage(path string) *packages.Package {
-	cfg := &packages.Config{
-		Mode: packageLoadingMode,
+// unexportedTypes finds the named types referenced by the type that aren't
+// exported by their package, and so can only be referred to from within it.
+func unexportedTypes(typ types.Type, found func(*types.TypeName)) {
+	switch typ := typ.(type) {
+	case *types.Named:
+		if obj := typ.Obj(); obj.Pkg() != nil && !obj.Exported() {
+			found(obj)
+		}
+		for i := 0; i < typ.TypeArgs().Len(); i++ {
+			unexportedTypes(typ.TypeArgs().At(i), found)
+		}
+	case *types.Pointer:
+		unexportedTypes(typ.Elem(), found)
+	case *types.Slice:
+		unexportedTypes(typ.Elem(), found)
+	case *types.Array:
+		unexportedTypes(typ.Elem(), found)
+	case *types.Chan:
+		unexportedTypes(typ.Elem(), found)
+	case *types.Map:
+		unexportedTypes(typ.Key(), found)
+		unexportedTypes(typ.Elem(), found)
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{typ.Params(), typ.Results()} {
+			for i := 0; i < tuple.Len(); i++ {
+				unexportedTypes(tuple.At(i).Type(), found)
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			unexportedTypes(typ.Field(i).Type(), found)
+		}
+	case *types.Interface:
+		for i := 0; i < typ.NumExplicitMethods(); i++ {
+			unexportedTypes(typ.ExplicitMethod(i).Type(), found)
+		}
+		for i := 0; i < typ.NumEmbeddeds(); i++ {
+			unexportedTypes(typ.EmbeddedType(i), found)
+		}
+	case *types.Union:
+		for i := 0; i < typ.Len(); i++ {
+			unexportedTypes(typ.Term(i).Type(), found)
+		}
+	default:
+		// the type an alias denotes is the one generated
+		if actual := interpreter.Unalias(typ); actual != typ {
+			unexportedTypes(actual, found)
+		}
 	}
-	pkgs, err := packages.Load(cfg, path)
+}
+
+// methodDocs collects the doc comments of the methods of the interface from
+// the syntax of the packages declaring them, which may be embedded from
+// other packages.
+func methodDocs(declaring *packages.Package, iface *types.Interface) interpreter.MethodDocs {
+	declarers := make(map[string]bool)
+	for m := 0; m < iface.NumMethods(); m++ {
+		if pkg := iface.Method(m).Pkg(); pkg != nil {
+			declarers[pkg.Path()] = true
+		}
+	}
+	docs := make(interpreter.MethodDocs)
+	packages.Visit([]*packages.Package{declaring}, nil, func(pkg *packages.Package) {
+		if declarers[pkg.PkgPath] {
+			interpreter.CollectMethodDocs(docs, pkg.Syntax)
+		}
+	})
+	return docs
+}
+
+// position locates the source of a problem for its error, relative to the
+// working directory when it lies below it, as the compiler reports it.
+func position(pkg *packages.Package, pos token.Pos) token.Position {
+	position := pkg.Fset.Position(pos)
+	wd, err := os.Getwd()
 	if err != nil {
-		failErr(err)
+		return position
 	}
-	if len(pkgs) != 1 {
-		failErr(fmt.Errorf("error: %d packages found", len(pkgs)))
+	if rel, err := filepath.Rel(wd, position.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+		position.Filename = rel
 	}
+	return position
+}
 
-	return pkgs[0]
+// inferMiddleware finds the type declared as func(X) X for the service in
+// the package, defaulting to ${TypeName}Middleware for it to be declared.
+func inferMiddleware(pkg *types.Package, service types.Object) string {
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if checkMiddlewareType(service, scope.Lookup(name)) == nil {
+			return name
+		}
+	}
+	return service.Name() + "Middleware"
 }
 
-func failErr(err error) {
+// checkMiddlewareType verifies the middleware is declared as a function
+// taking and returning the service, sharing its type parameters if any.
+func checkMiddlewareType(service, middleware types.Object) error {
+	bad := errors.BadMiddlewareTypeErr{Obj: middleware, Service: service}
+	if _, ok := middleware.(*types.TypeName); !ok {
+		return bad
+	}
+	sig, ok := middleware.Type().Underlying().(*types.Signature)
+	if !ok || sig.Params().Len() != 1 || sig.Results().Len() != 1 || sig.Variadic() {
+		return bad
+	}
+	expected := service.Type()
+	if named, ok := expected.(*types.Named); ok && named.TypeParams().Len() > 0 {
+		middlewareNamed, ok := middleware.Type().(*types.Named)
+		if !ok || middlewareNamed.TypeParams().Len() != named.TypeParams().Len() {
+			return bad
+		}
+		typeArgs := make([]types.Type, named.TypeParams().Len())
+		for t := range typeArgs {
+			typeArgs[t] = middlewareNamed.TypeParams().At(t)
+		}
+		instance, err := types.Instantiate(nil, named, typeArgs, false)
+		if err != nil {
+			return bad
+		}
+		expected = instance
+	}
+	if !types.Identical(sig.Params().At(0).Type(), expected) || !types.Identical(sig.Results().At(0).Type(), expected) {
+		return bad
+	}
+	return nil
+}
+
+// packageCaches holds the packageCache of every directory generated from.
+type packageCaches struct {
+	ctx   context.Context
+	mu    sync.Mutex
+	byDir map[string]*packageCache
+}
+
+func newPackageCaches(ctx context.Context) *packageCaches {
+	return &packageCaches{ctx: ctx, byDir: make(map[string]*packageCache)}
+}
+
+func (c *packageCaches) of(dir string) *packageCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cache, ok := c.byDir[dir]
+	if !ok {
+		cache = newPackageCache(c.ctx, dir)
+		c.byDir[dir] = cache
+	}
+	return cache
+}
+
+// packageCache loads each package only once for all the Files
+// generated from the same directory.
+type packageCache struct {
+	ctx  context.Context
+	dir  string
+	mu   sync.Mutex
+	pkgs map[string]*packages.Package
+	errs map[string]error
+}
+
+// seed the cache with the package of its directory, which was loaded
+// along with others.
+func (c *packageCache) seed(pkg *packages.Package) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pkgs["."] = pkg
+	c.pkgs[pkg.PkgPath] = pkg
+}
+
+func newPackageCache(ctx context.Context, dir string) *packageCache {
+	return &packageCache{
+		ctx:  ctx,
+		dir:  dir,
+		pkgs: make(map[string]*packages.Package),
+		errs: make(map[string]error),
+	}
+}
+
+// load the package matching the pattern, where "." is the package of the
+// directory. Its dependencies are reused so that their types are identical
+// to those referred to by the package of the directory.
+func (c *packageCache) load(pattern string) (*packages.Package, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadLocked(pattern)
+}
+
+func (c *packageCache) loadLocked(pattern string) (*packages.Package, error) {
+	if pkg, ok := c.pkgs[pattern]; ok {
+		return pkg, nil
+	}
+	// a package failing to load is reported once for every File
+	if err, ok := c.errs[pattern]; ok {
+		return nil, err
+	}
+	if pattern != "." {
+		local, err := c.loadLocked(".")
+		if err != nil {
+			return nil, err
+		}
+		packages.Visit([]*packages.Package{local}, nil, func(dep *packages.Package) {
+			c.pkgs[dep.PkgPath] = dep
+		})
+		if pkg, ok := c.pkgs[pattern]; ok {
+			return pkg, nil
+		}
+	}
+	pkg, err := loadPackage(c.ctx, c.dir, pattern)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		c.errs[pattern] = err
+		return nil, err
 	}
+	c.pkgs[pattern] = pkg
+	c.pkgs[pkg.PkgPath] = pkg
+	return pkg, nil
+}
+
+// loadPackage loads the single package matching the pattern, which is
+// resolved relative to the directory.
+func loadPackage(ctx context.Context, dir, pattern string) (*packages.Package, error) {
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packageLoadingMode,
+		Dir:     dir,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, errors.PackageLoadErr{Dir: dir, Pattern: pattern, Err: err}
+	}
+	if len(pkgs) != 1 {
+		return nil, errors.PackageLoadErr{Dir: dir, Pattern: pattern, Err: fmt.Errorf("%d packages found", len(pkgs))}
+	}
+
+	return pkgs[0], nil
 }
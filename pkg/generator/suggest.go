@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"go/types"
+	"strings"
+)
+
+// suggest finds the type declared in the scope that the name is most likely
+// a misspelling of, or nothing when none is close enough. Unexported types
+// are only suggested when they can be referred to.
+func suggest(scope *types.Scope, name string, exportedOnly bool) string {
+	best, bestDistance := "", len(name)/2+1
+	for _, candidate := range scope.Names() {
+		obj := scope.Lookup(candidate)
+		if _, ok := obj.(*types.TypeName); !ok || exportedOnly && !obj.Exported() {
+			continue
+		}
+		if strings.EqualFold(candidate, name) {
+			return candidate
+		}
+		if distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// levenshtein counts the single character edits turning a into b.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,265 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/gabizou/middleware-generator/pkg/errors"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+	"github.com/gabizou/middleware-generator/pkg/plugin"
+)
+
+func init() { //nolint:gochecknoinits
+	Register("template", templateCustomizer{})
+}
+
+// templateCustomizer generates the middleware described by the sections of
+// a text/template file, given by the file option, as in
+//
+//	-plugin template:file=audit.tmpl
+//
+// relative to the directory of the package given to the generator, that of
+// the configuration file, or that of the annotated interface when
+// discovered, see File.PluginDir. The middleware is named after the
+// file unless given the name option, e.g. auditService and NewServiceAudit.
+// Each section is optional:
+//
+//	{{define "imports"}}  the import paths of the packages referenced, one per
+//	                      line, optionally preceded by the name imported as
+//	{{define "params"}}   the parameters of the factory, one per line as
+//	                      <name> <type> [<field>], stored on the struct in the
+//	                      field when given, e.g. logger *slog.Logger lg
+//	{{define "fields"}}   additional fields of the struct as <name> <type>,
+//	                      assigned from the local variables of the factory
+//	{{define "factory"}}  the statements of the factory
+//	{{define "method"}}   the statements of the body of each method, which
+//	                      forwards the call untouched when empty
+//
+// The sections are executed with a templateData, whose Service, Methods and
+// Options are those of a plugin.Request, while the Method is set for the
+// method section, see plugin.Method for what it describes.
+type templateCustomizer struct {
+	name     string
+	path     string
+	template *template.Template
+}
+
+// templateData is the data the sections of a template are executed with.
+type templateData struct {
+	plugin.Request
+	Method plugin.Method
+}
+
+var templateFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+	"join":  strings.Join,
+}
+
+func (t templateCustomizer) FileNamePrefix() string {
+	return t.name
+}
+
+func (t templateCustomizer) FactorySuffix() string {
+	return factorySuffix(t.name)
+}
+
+func (t templateCustomizer) ConfigureModel(model *ServiceModel) {
+	model.StructPrefix = t.name + "%s"
+}
+
+func (t templateCustomizer) GetRequiredImportNames() map[string]string {
+	return nil
+}
+
+// GenerateFunctionImplementation forwards the call untouched, as the
+// methods are generated by the Customizer prepared for the model.
+func (t templateCustomizer) GenerateFunctionImplementation(
+	builder *jen.Statement,
+	service *ServiceModel,
+	method interpreter.DeclaredFunction,
+) jen.Code {
+	return forwardBlock(builder, service, method)
+}
+
+// WithOptions configures the template file, see templateCustomizer.
+func (t templateCustomizer) WithOptions(options map[string]string) (Customizer, error) {
+	for key, value := range options {
+		switch key {
+		case "file":
+			t.path = value
+		case "name":
+			t.name = value
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	if t.path == "" {
+		return nil, fmt.Errorf("missing the file option")
+	}
+	if t.name == "" {
+		t.name = strings.TrimSuffix(filepath.Base(t.path), filepath.Ext(t.path))
+	}
+	if !token.IsIdentifier(t.name) {
+		return nil, fmt.Errorf("name %q is not an identifier", t.name)
+	}
+	return t, nil
+}
+
+// load parses the template file, relative to the directory when it isn't
+// absolute.
+func (t templateCustomizer) load(dir string) (*template.Template, error) {
+	path := t.path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(src))
+}
+
+func (t templateCustomizer) customizerName() string {
+	return t.name
+}
+
+// prepare executes the sections of the template for the model.
+func (t templateCustomizer) prepare(g *Generator, model *ServiceModel) (Customizer, error) {
+	if t.path == "" {
+		return nil, errors.CustomizerOptionErr{Name: "template", Err: fmt.Errorf("missing the file option")}
+	}
+	var err error
+	if t.template, err = t.load(g.pluginDir); err != nil {
+		return nil, errors.TemplateErr{Path: t.path, Type: model.TypeName, Err: err}
+	}
+	req := pluginRequest(g, model, t.name, nil)
+	resp, err := t.respond(req)
+	if err == nil {
+		var prepared Customizer
+		if prepared, err = respondedPlugin(t.name, req, resp); err == nil {
+			return prepared, nil
+		}
+	}
+	return nil, errors.TemplateErr{Path: t.path, Type: model.TypeName, Err: err}
+}
+
+// respond executes the sections of the template as a plugin would respond.
+func (t templateCustomizer) respond(req plugin.Request) (plugin.Response, error) {
+	data := templateData{Request: req}
+	resp := plugin.Response{Imports: make(map[string]string), Methods: make(map[string]string)}
+	lines, err := t.section("imports", data)
+	if err != nil {
+		return resp, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 1:
+			resp.Imports[fields[0]] = path.Base(fields[0])
+		case 2:
+			resp.Imports[fields[1]] = fields[0]
+		default:
+			return resp, fmt.Errorf("imports: malformed import %q", line)
+		}
+	}
+	imports, err := importPaths(req.Imports, resp.Imports)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Parameters, err = t.parameters("params", data, imports); err != nil {
+		return resp, err
+	}
+	if resp.Fields, err = t.parameters("fields", data, imports); err != nil {
+		return resp, err
+	}
+	if resp.Factory, err = t.execute("factory", data); err != nil {
+		return resp, err
+	}
+	for _, method := range req.Methods {
+		data.Method = method
+		body, err := t.execute("method", data)
+		if err != nil {
+			return resp, err
+		}
+		if strings.TrimSpace(body) != "" {
+			resp.Methods[method.Name] = body
+		}
+	}
+	return resp, nil
+}
+
+// parameters parses the lines of the params or fields section as
+// <name> <type> [<field>], the type being a pointer to a named type
+// qualified by the name of its import, a named type or a basic type.
+func (t templateCustomizer) parameters(section string, data templateData, imports map[string]string) ([]plugin.Parameter, error) {
+	lines, err := t.section(section, data)
+	if err != nil {
+		return nil, err
+	}
+	var params []plugin.Parameter
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 || (section == "fields" && len(fields) > 2) {
+			return nil, fmt.Errorf("%s: malformed parameter %q", section, line)
+		}
+		param := plugin.Parameter{Name: fields[0]}
+		typeName := fields[1]
+		if strings.HasPrefix(typeName, "*") {
+			param.Pointer = true
+			typeName = typeName[1:]
+		}
+		if pkg, name, qualified := strings.Cut(typeName, "."); qualified {
+			if param.Path = imports[pkg]; param.Path == "" {
+				return nil, fmt.Errorf("%s: %s is not imported", section, pkg)
+			}
+			typeName = name
+		}
+		if !token.IsIdentifier(param.Name) || !token.IsIdentifier(typeName) {
+			return nil, fmt.Errorf("%s: unsupported parameter %q", section, line)
+		}
+		param.Type = typeName
+		switch {
+		case section == "fields":
+			param.Field = param.Name
+		case len(fields) == 3:
+			param.Field = fields[2]
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// section executes the section, splitting its non-blank lines.
+func (t templateCustomizer) section(name string, data templateData) ([]string, error) {
+	out, err := t.execute(name, data)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// execute executes the section, which renders nothing when undefined.
+func (t templateCustomizer) execute(name string, data templateData) (string, error) {
+	if t.template.Lookup(name) == nil {
+		return "", nil
+	}
+	var out bytes.Buffer
+	if err := t.template.ExecuteTemplate(&out, name, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
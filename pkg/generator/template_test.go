@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/gabizou/middleware-generator/pkg/plugin"
+)
+
+func TestTemplateRespond(t *testing.T) {
+	req := plugin.Request{
+		Version: plugin.Version,
+		Name:    "audit",
+		Service: plugin.Service{TypeName: "Service", Package: "service", StructName: "auditService", StructPtr: "a", ServicePtr: "s"},
+		Methods: []plugin.Method{
+			{Name: "Find", Results: []plugin.Variable{{Name: "r0", Type: "store.User"}}, Call: "a.s.Find()"},
+			{Name: "Ping", Call: "a.s.Ping()"},
+		},
+		Imports: map[string]string{"example.com/store": "store"},
+	}
+	tests := []struct {
+		name string
+		src  string
+		want plugin.Response
+		// wantErr is contained by the error
+		wantErr string
+	}{
+		{
+			name: "undefined sections",
+			src:  ``,
+			want: plugin.Response{Imports: map[string]string{}, Methods: map[string]string{}},
+		},
+		{
+			name: "imports",
+			src: `{{define "imports"}}
+log/slog
+zipkin github.com/openzipkin/zipkin-go
+{{end}}`,
+			want: plugin.Response{
+				Imports: map[string]string{"log/slog": "slog", "github.com/openzipkin/zipkin-go": "zipkin"},
+				Methods: map[string]string{},
+			},
+		},
+		{
+			name:    "malformed import",
+			src:     `{{define "imports"}}zipkin github.com/openzipkin/zipkin-go extra{{end}}`,
+			wantErr: `imports: malformed import "zipkin github.com/openzipkin/zipkin-go extra"`,
+		},
+		{
+			name:    "import naming another package",
+			src:     `{{define "imports"}}store example.com/other/store{{end}}`,
+			wantErr: "imports: store names both example.com/store and example.com/other/store",
+		},
+		{
+			name: "params",
+			src: `{{define "imports"}}log/slog{{end}}
+{{define "params"}}
+logger *slog.Logger lg
+repo store.Repository
+limit int
+{{end}}`,
+			want: plugin.Response{
+				Imports: map[string]string{"log/slog": "slog"},
+				Parameters: []plugin.Parameter{
+					{Name: "logger", Type: "Logger", Path: "log/slog", Field: "lg", Pointer: true},
+					{Name: "repo", Type: "Repository", Path: "example.com/store"},
+					{Name: "limit", Type: "int"},
+				},
+				Methods: map[string]string{},
+			},
+		},
+		{
+			name:    "param of a package not imported",
+			src:     `{{define "params"}}logger *slog.Logger{{end}}`,
+			wantErr: "params: slog is not imported",
+		},
+		{
+			name:    "malformed param",
+			src:     `{{define "params"}}logger{{end}}`,
+			wantErr: `params: malformed parameter "logger"`,
+		},
+		{
+			name:    "unsupported param",
+			src:     `{{define "params"}}loggers []int{{end}}`,
+			wantErr: `params: unsupported parameter "loggers []int"`,
+		},
+		{
+			name: "fields",
+			src:  `{{define "fields"}}calls int{{end}}`,
+			want: plugin.Response{
+				Imports: map[string]string{},
+				Fields:  []plugin.Parameter{{Name: "calls", Type: "int", Field: "calls"}},
+				Methods: map[string]string{},
+			},
+		},
+		{
+			name:    "field stored in another field",
+			src:     `{{define "fields"}}calls int count{{end}}`,
+			wantErr: `fields: malformed parameter "calls int count"`,
+		},
+		{
+			name: "factory and methods",
+			src: `{{define "factory"}}calls := 0{{end}}
+{{define "method"}}{{if .Method.Results}}// {{.Service.StructName}}.{{.Method.Name}}
+{{.Method.Forward}}{{end}}{{end}}`,
+			want: plugin.Response{
+				Imports: map[string]string{},
+				Factory: "calls := 0",
+				Methods: map[string]string{"Find": "// auditService.Find\nreturn a.s.Find()"},
+			},
+		},
+		{
+			name:    "failing section",
+			src:     `{{define "factory"}}{{.Undefined}}{{end}}`,
+			wantErr: `can't evaluate field Undefined`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl, err := template.New("t").Funcs(templateFuncs).Parse(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := templateCustomizer{name: "audit", template: tmpl}.respond(req)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got %v, want %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp, test.want) {
+				t.Errorf("got %+v, want %+v", resp, test.want)
+			}
+		})
+	}
+}
+
+func TestTemplateOptions(t *testing.T) {
+	tests := []struct {
+		options map[string]string
+		name    string
+		wantErr string
+	}{
+		{options: map[string]string{"file": "templates/audit.tmpl"}, name: "audit"},
+		{options: map[string]string{"file": "audit.tmpl", "name": "trail"}, name: "trail"},
+		{options: map[string]string{"name": "trail"}, wantErr: "missing the file option"},
+		{options: map[string]string{"file": "audit-log.tmpl"}, wantErr: `name "audit-log" is not an identifier`},
+		{options: map[string]string{"file": "audit.tmpl", "path": "."}, wantErr: `unknown option "path"`},
+	}
+	for _, test := range tests {
+		customizer, err := templateCustomizer{}.WithOptions(test.options)
+		if test.wantErr != "" {
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("%v: got %v, want %s", test.options, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", test.options, err)
+			continue
+		}
+		if got := customizer.FileNamePrefix(); got != test.name {
+			t.Errorf("%v: named %s, want %s", test.options, got, test.name)
+		}
+	}
+}
+
+func TestTemplateLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "audit.tmpl"), []byte(`{{define "factory"}}{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"audit.tmpl", filepath.Join(dir, "audit.tmpl")} {
+		tmpl, err := templateCustomizer{path: path}.load(dir)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if tmpl.Lookup("factory") == nil {
+			t.Errorf("%s: the factory section is undefined", path)
+		}
+	}
+	if _, err := (templateCustomizer{path: "audit.tmpl"}).load(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("got %v, want the file not to exist", err)
+	}
+}
@@ -0,0 +1,14 @@
+package account
+
+// Account signs users in, without logging their secrets.
+type Account interface {
+	//middleware:redact password
+	SignIn(user, password string) (token string, err error)
+	// Reset redacts several parameters, one of them twice, and ignores a
+	// parameter it doesn't declare.
+	//middleware:redact token, pin
+	//middleware:redact pin unknown
+	Reset(user, token string, pin int) error
+	//middleware:redact
+	Rename(user, name string) error
+}
@@ -0,0 +1,6 @@
+package closer
+
+// Closer releases what it holds.
+type Closer interface {
+	Close() error
+}
@@ -0,0 +1,16 @@
+package conn
+
+import "github.com/gabizou/middleware-generator/pkg/generator/testdata/closer"
+
+// Flusher flushes what was sent.
+type Flusher interface {
+	//middleware:skip
+	Flush() error
+}
+
+// Conn embeds an interface of its own package along with one of another.
+type Conn interface {
+	closer.Closer
+	Flusher
+	Send(msg string) error
+}
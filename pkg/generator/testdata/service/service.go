@@ -0,0 +1,20 @@
+package service
+
+type User struct {
+	Name string
+}
+
+// Service finds users.
+type Service interface {
+	Find(id string) (User, error)
+	//middleware:redact password
+	Login(name, password string) error
+	//middleware:name service.names
+	Names(prefix string, limit ...int) []string
+	//middleware:skip
+	Ping()
+}
+
+// Thing is not an interface.
+type Thing struct {
+}
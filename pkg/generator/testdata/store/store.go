@@ -0,0 +1,21 @@
+package store
+
+type user struct {
+	Name string
+}
+
+type role int
+
+// Users are stored along with their roles.
+type Users interface {
+	Count() int
+	Find(id string) (*user, error)
+	Roles(id string) []role
+}
+
+// Roles are granted to users.
+type Roles interface {
+	Grant(id string, r role) error
+}
+
+type RolesMiddleware func(Roles) error
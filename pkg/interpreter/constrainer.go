@@ -0,0 +1,51 @@
+package interpreter
+
+import (
+	"go/types"
+
+	"github.com/dave/jennifer/jen"
+)
+
+func (t *typeParamLiteral) Constraint() jen.Code {
+	return constraintOf(t.param.Constraint())
+}
+
+// constraintOf renders a type constraint, unwrapping the implicit interface
+// of a constraint such as ~int | ~string, and using any for the empty
+// interface.
+func constraintOf(constraint types.Type) jen.Code {
+	switch kind := constraint.(type) {
+	case *types.Interface:
+		if kind.Empty() {
+			return jen.Any()
+		}
+		if kind.IsImplicit() && kind.NumEmbeddeds() == 1 {
+			return constraintOf(kind.EmbeddedType(0))
+		}
+		var elems []jen.Code
+		for e := 0; e < kind.NumEmbeddeds(); e++ {
+			elems = append(elems, constraintOf(kind.EmbeddedType(e)))
+		}
+		for m := 0; m < kind.NumExplicitMethods(); m++ {
+			elems = append(elems, deriveParameter(0, kind.ExplicitMethod(m).Type()).(*functionLiteral).
+				appendFunction(jen.Id(kind.ExplicitMethod(m).Name())))
+		}
+		return jen.Interface(elems...)
+	case *types.Union:
+		terms := make([]jen.Code, kind.Len())
+		for t := 0; t < kind.Len(); t++ {
+			term := kind.Term(t)
+			code := jen.Null()
+			if term.Tilde() {
+				code.Op("~")
+			}
+			terms[t] = code.Add(deriveParameter(0, term.Type()).AsReturnType())
+		}
+		return jen.Union(terms...)
+	}
+	// Type aliases, such as any, stand in for the type they denote
+	if actual := Unalias(constraint); actual != constraint {
+		return constraintOf(actual)
+	}
+	return deriveParameter(0, constraint).AsReturnType()
+}
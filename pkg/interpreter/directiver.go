@@ -0,0 +1,118 @@
+package interpreter
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// DirectivePrefix starts the comments in the doc of an interface method that
+// configure the generated middleware, such as //middleware:skip tracer.
+const DirectivePrefix = "//middleware:"
+
+// Directive is a single //middleware: comment, split into its name and the
+// arguments following it, which are separated by spaces or commas.
+type Directive struct {
+	Name string
+	Args []string
+}
+
+// Directives are those declared in the doc of a method, in order.
+type Directives []Directive
+
+// ParseDirectives finds the directives of a doc comment, which may be nil.
+func ParseDirectives(doc *ast.CommentGroup) Directives {
+	if doc == nil {
+		return nil
+	}
+	var directives Directives
+	for _, comment := range doc.List {
+		text := strings.TrimPrefix(comment.Text, DirectivePrefix)
+		if text == comment.Text {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		directives = append(directives, Directive{Name: fields[0], Args: fields[1:]})
+	}
+	return directives
+}
+
+// Skips reports whether the method is left untouched by the customizer of
+// the given name, by way of //middleware:skip naming it or naming none.
+func (d Directives) Skips(customizer string) bool {
+	for _, directive := range d {
+		if directive.Name != "skip" {
+			continue
+		}
+		if len(directive.Args) == 0 {
+			return true
+		}
+		for _, arg := range directive.Args {
+			if arg == customizer {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Value is the first argument of the first directive of the given name,
+// such as the span name of //middleware:name repo.find.
+func (d Directives) Value(name string) (string, bool) {
+	for _, directive := range d {
+		if directive.Name == name && len(directive.Args) > 0 {
+			return directive.Args[0], true
+		}
+	}
+	return "", false
+}
+
+// Values are the arguments of every directive of the given name, such as
+// the parameters of //middleware:redact password, token.
+func (d Directives) Values(name string) []string {
+	var values []string
+	for _, directive := range d {
+		if directive.Name == name {
+			values = append(values, directive.Args...)
+		}
+	}
+	return values
+}
+
+// Contains reports whether the value is among the Values of the name.
+func (d Directives) Contains(name, value string) bool {
+	for _, v := range d.Values(name) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MethodDocs maps the position of each method name declared by an
+// interface to the doc comment of that method.
+type MethodDocs map[token.Pos]*ast.CommentGroup
+
+// CollectMethodDocs finds the doc comment of every method declared by the
+// interfaces of the files.
+func CollectMethodDocs(docs MethodDocs, files []*ast.File) {
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			iface, ok := node.(*ast.InterfaceType)
+			if !ok || iface.Methods == nil {
+				return true
+			}
+			for _, field := range iface.Methods.List {
+				for _, name := range field.Names {
+					docs[name.Pos()] = field.Doc
+				}
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package interpreter
+
+import (
+	"go/ast"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want Directives
+	}{
+		{
+			name: "no doc",
+			want: nil,
+		},
+		{
+			name: "plain comments",
+			doc:  "// Find finds a user.\n// middleware:skip is not a directive without the slashes next to it",
+			want: nil,
+		},
+		{
+			name: "arguments separated by commas and spaces",
+			doc:  "//middleware:redact password, token\tpin",
+			want: Directives{{Name: "redact", Args: []string{"password", "token", "pin"}}},
+		},
+		{
+			name: "without arguments",
+			doc:  "// Ping pings.\n//middleware:skip",
+			want: Directives{{Name: "skip", Args: []string{}}},
+		},
+		{
+			name: "malformed",
+			doc:  "//middleware:\n//middleware: ,\n//middleware:  name  service.find ",
+			want: Directives{{Name: "name", Args: []string{"service.find"}}},
+		},
+		{
+			name: "unknown",
+			doc:  "//middleware:retry 3",
+			want: Directives{{Name: "retry", Args: []string{"3"}}},
+		},
+		{
+			name: "duplicated",
+			doc:  "//middleware:redact password\n//middleware:skip tracer\n//middleware:redact token,password",
+			want: Directives{
+				{Name: "redact", Args: []string{"password"}},
+				{Name: "skip", Args: []string{"tracer"}},
+				{Name: "redact", Args: []string{"token", "password"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var doc *ast.CommentGroup
+			if test.doc != "" {
+				doc = &ast.CommentGroup{}
+				for _, line := range strings.Split(test.doc, "\n") {
+					doc.List = append(doc.List, &ast.Comment{Text: line})
+				}
+			}
+			if got := ParseDirectives(doc); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	d := Directives{
+		{Name: "redact", Args: []string{"password"}},
+		{Name: "skip", Args: []string{"tracer", "otel"}},
+		{Name: "name", Args: []string{"service.find", "ignored"}},
+		{Name: "redact", Args: []string{"token", "password"}},
+		{Name: "name", Args: []string{"service.other"}},
+	}
+	if got, want := d.Values("redact"), []string{"password", "token", "password"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values(redact) = %v, want %v", got, want)
+	}
+	if !d.Contains("redact", "token") || d.Contains("redact", "name") {
+		t.Error("Contains(redact) reports the wrong parameters")
+	}
+	if value, ok := d.Value("name"); !ok || value != "service.find" {
+		t.Errorf("Value(name) = %q, %t, want the first one", value, ok)
+	}
+	if _, ok := d.Value("retry"); ok {
+		t.Error("Value(retry) found an undeclared directive")
+	}
+	for customizer, want := range map[string]bool{"tracer": true, "otel": true, "logger": false} {
+		if got := d.Skips(customizer); got != want {
+			t.Errorf("Skips(%s) = %t, want %t", customizer, got, want)
+		}
+	}
+	if !(Directives{{Name: "skip"}}).Skips("logger") {
+		t.Error("a skip naming no customizer doesn't skip every one")
+	}
+	if (Directives(nil)).Skips("logger") {
+		t.Error("no directives skip the logger")
+	}
+}
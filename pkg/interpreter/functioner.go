@@ -1,6 +1,9 @@
 package interpreter
 
 import (
+	"fmt"
+	"go/types"
+
 	"github.com/dave/jennifer/jen"
 )
 
@@ -10,6 +13,23 @@ type DeclaredFunction interface {
 	Parameters() []NamedVariable
 	Returns() []NamedVariable
 	ReturnDefinition() jen.Code
+	// Variadic reports whether the last of the Parameters is variadic,
+	// which is declared as ...T and forwarded as name...
+	Variadic() bool
+	// ContextParameter returns the first parameter typed as context.Context,
+	// or nil when the method does not accept one.
+	ContextParameter() NamedVariable
+	// ReturnsError reports whether the last result of the method is an error.
+	ReturnsError() bool
+	// ResultNames provides a local variable name for each of the Returns,
+	// none of which collide with the Parameters. A trailing error is
+	// preferably named err.
+	ResultNames() []string
+	// LocalName suffixes the desired name of a local variable until it
+	// no longer collides with any of the Parameters.
+	LocalName(desired string) string
+	// Directives are the //middleware: comments in the doc of the method.
+	Directives() Directives
 }
 
 func (d *declaredFunc) FunctionName() string {
@@ -20,6 +40,10 @@ func (d *declaredFunc) Parameters() []NamedVariable {
 	return d.params
 }
 
+func (d *declaredFunc) Variadic() bool {
+	return d.sig.Variadic()
+}
+
 func (d *declaredFunc) Returns() []NamedVariable {
 	return d.returns
 }
@@ -45,3 +69,56 @@ func (d *declaredFunc) ReturnDefinition() jen.Code {
 	}
 	return builder
 }
+
+func (d *declaredFunc) ContextParameter() NamedVariable {
+	for _, variable := range d.params {
+		if typed, ok := Unalias(variable.UnderlyingType()).(*types.Named); ok {
+			obj := typed.Obj()
+			if obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context" {
+				return variable
+			}
+		}
+	}
+	return nil
+}
+
+func (d *declaredFunc) ReturnsError() bool {
+	if len(d.returns) == 0 {
+		return false
+	}
+	last := d.returns[len(d.returns)-1].UnderlyingType()
+	return types.Identical(last, types.Universe.Lookup("error").Type())
+}
+
+func (d *declaredFunc) ResultNames() []string {
+	names := make([]string, len(d.returns))
+	for i := range d.returns {
+		desired := fmt.Sprintf("r%d", i)
+		if i == len(d.returns)-1 && d.ReturnsError() {
+			desired = "err"
+		}
+		names[i] = d.LocalName(desired)
+	}
+	return names
+}
+
+func (d *declaredFunc) LocalName(desired string) string {
+	name := desired
+	for i := 1; d.hasParameter(name); i++ {
+		name = fmt.Sprintf("%s%d", desired, i)
+	}
+	return name
+}
+
+func (d *declaredFunc) Directives() Directives {
+	return d.directives
+}
+
+func (d *declaredFunc) hasParameter(name string) bool {
+	for _, p := range d.params {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
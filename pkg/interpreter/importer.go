@@ -11,8 +11,13 @@ func (n *namedLiteral) AssignImports(f *jen.File) {
 	if pkg != nil {
 		f.ImportName(pkg.Path(), pkg.Name())
 	}
+	for _, arg := range n.typeArgs {
+		arg.AssignImports(f)
+	}
 }
 
+func (t *typeParamLiteral) AssignImports(_ *jen.File) {}
+
 func (f *functionLiteral) AssignImports(file *jen.File) {
 	for _, param := range f.params {
 		param.AssignImports(file)
@@ -47,6 +52,14 @@ func (s *sliceLiteral) AssignImports(f *jen.File) {
 	s.inner.AssignImports(f)
 }
 
+func (a *arrayLiteral) AssignImports(f *jen.File) {
+	a.inner.AssignImports(f)
+}
+
+func (c *chanLiteral) AssignImports(f *jen.File) {
+	c.inner.AssignImports(f)
+}
+
 func (p *pointerLiteral) AssignImports(_ *jen.File) {}
 
 func (m *mapLiteral) AssignImports(file *jen.File) {
@@ -3,6 +3,7 @@ package interpreter
 import (
 	"fmt"
 	"go/types"
+	"io"
 	"strconv"
 	"strings"
 
@@ -21,39 +22,105 @@ type InterpretedVariable interface {
 	AssignImports(file *jen.File)
 }
 
-func DeriveInterface(iface *types.Interface) []DeclaredFunction {
+// TypeParameter is a type parameter declared by a generic type, which
+// renders as its name when used as a type.
+type TypeParameter interface {
+	InterpretedVariable
+	// Constraint renders the constraint of the type parameter, as
+	// used in a type parameter list.
+	Constraint() jen.Code
+}
+
+// DeriveTypeParameters interprets the type parameters of a generic type,
+// returning nil for a type that isn't generic.
+func DeriveTypeParameters(list *types.TypeParamList) []TypeParameter {
+	var params []TypeParameter
+	for t := 0; t < list.Len(); t++ {
+		params = append(params, &typeParamLiteral{param: list.At(t)})
+	}
+	return params
+}
+
+// DeriveInterface interprets the methods of the interface, failing with
+// the error of a type that cannot be interpreted. The Directives of each
+// method are parsed from its doc comment found in the docs.
+func DeriveInterface(iface *types.Interface, docs MethodDocs) (functions []DeclaredFunction, err error) {
+	defer func() {
+		// deriveParameter bails out of its recursion by panicking
+		switch r := recover().(type) {
+		case nil:
+		case errors.TypeTooComplexErr:
+			err = r
+		case errors.BadMethodSignatureTypeErr:
+			err = r
+		default:
+			panic(r)
+		}
+	}()
 	parameter := deriveParameter(0, iface)
 	derivedInterface, ok := parameter.(*interfaceLiteral)
 	if !ok {
-		return nil
+		return nil, nil
+	}
+	for _, function := range derivedInterface.functions {
+		declared := function.(*declaredFunc)
+		declared.directives = ParseDirectives(docs[declared.m.Pos()])
 	}
-	return derivedInterface.functions
+	return derivedInterface.functions, nil
 }
 
 const _recursiveTypeResolutionLimit = 10
 
+// debugOutput receives the trace of the types as they are derived.
+var debugOutput io.Writer = io.Discard
+
+// SetDebugOutput traces the derivation of the types to the writer, such as
+// os.Stderr, or discards the trace for a nil writer.
+func SetDebugOutput(w io.Writer) {
+	if w == nil {
+		w = io.Discard
+	}
+	debugOutput = w
+}
+
 func deriveParameter(attempts int32, variable types.Type) InterpretedVariable {
 	if attempts > _recursiveTypeResolutionLimit {
-		panic("got too complicated, don't make 5 nested types")
+		panic(errors.TypeTooComplexErr{Type: variable})
 	}
 	indent := strings.Repeat(" ", int(attempts))
 	switch kind := variable.(type) {
 	case *types.Pointer:
 		p := &pointerLiteral{inner: deriveParameter(attempts+1, kind.Elem())}
-		fmt.Printf("%s%s\n", indent, p.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, p.DebugString())
 		return p
 	case *types.Basic:
 		p := &primitive{goType: kind}
-		fmt.Printf("%s%s\n", indent, p.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, p.DebugString())
 		return p
 	case *types.Named:
 		n := &namedLiteral{named: kind}
-		fmt.Printf("%s%s\n", indent, n.DebugString())
+		typeArgs := kind.TypeArgs()
+		for t := 0; t < typeArgs.Len(); t++ {
+			n.typeArgs = append(n.typeArgs, deriveParameter(attempts+1, typeArgs.At(t)))
+		}
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, n.DebugString())
 		return n
+	case *types.TypeParam:
+		t := &typeParamLiteral{param: kind}
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, t.DebugString())
+		return t
 	case *types.Slice:
 		s := &sliceLiteral{inner: deriveParameter(attempts+1, kind.Elem())}
-		fmt.Printf("%s%s\n", indent, s.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, s.DebugString())
 		return s
+	case *types.Array:
+		a := &arrayLiteral{kind: kind, inner: deriveParameter(attempts+1, kind.Elem())}
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, a.DebugString())
+		return a
+	case *types.Chan:
+		c := &chanLiteral{kind: kind, inner: deriveParameter(attempts+1, kind.Elem())}
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, c.DebugString())
+		return c
 	case *types.Signature:
 		f := &functionLiteral{sig: kind}
 		params := make([]NamedVariable, kind.Params().Len())
@@ -66,6 +133,7 @@ func deriveParameter(attempts int32, variable types.Type) InterpretedVariable {
 				variable: param,
 				name:     param.Name(),
 				inner:    derivedParameter,
+				variadic: kind.Variadic() && p == kind.Params().Len()-1,
 			}
 		}
 		returns := make([]NamedVariable, kind.Results().Len())
@@ -81,21 +149,23 @@ func deriveParameter(attempts int32, variable types.Type) InterpretedVariable {
 		f.params = params
 		f.paramNames = paramNames
 		f.returns = returns
-		fmt.Printf("%s%s\n", indent, f.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, f.DebugString())
 		return f
 	case *types.Map:
 		k := deriveParameter(attempts+1, kind.Key())
 		v := deriveParameter(attempts+1, kind.Elem())
 		m := &mapLiteral{kind: kind, key: k, val: v}
-		fmt.Printf("%s%s\n", indent, m.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, m.DebugString())
 		return m
 	case *types.Interface:
-		fns := make([]DeclaredFunction, kind.NumExplicitMethods())
+		// The full method set includes those of embedded interfaces,
+		// even when declared in another package.
+		fns := make([]DeclaredFunction, kind.NumMethods())
 		i := &interfaceLiteral{
 			iface:     kind,
 			functions: fns,
 		}
-		for fn := 0; fn < kind.NumExplicitMethods(); fn++ {
+		for fn := 0; fn < kind.NumMethods(); fn++ {
 			m := kind.Method(fn)
 			sig, ok := m.Type().(*types.Signature)
 			if !ok {
@@ -120,6 +190,7 @@ func deriveParameter(attempts int32, variable types.Type) InterpretedVariable {
 					variable: param,
 					name:     name,
 					inner:    derivedParameter,
+					variadic: sig.Variadic() && p == params.Len()-1,
 				}
 			}
 			dc.params = derivedParams
@@ -150,7 +221,7 @@ func deriveParameter(attempts int32, variable types.Type) InterpretedVariable {
 			dc.returns = derivedRes
 			fns[fn] = dc
 		}
-		fmt.Printf("%s%s\n", indent, i.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, i.DebugString())
 		return i
 	case *types.Struct:
 		fields := make([]NamedVariable, kind.NumFields())
@@ -168,18 +239,42 @@ func deriveParameter(attempts int32, variable types.Type) InterpretedVariable {
 				trulyNamed: true,
 			}
 		}
-		fmt.Printf("%s%s\n", indent, s.DebugString())
+		_, _ = fmt.Fprintf(debugOutput, "%s%s\n", indent, s.DebugString())
 		return s
 	}
+	// Type aliases, such as any, stand in for the type they denote
+	if actual := Unalias(variable); actual != variable {
+		return deriveParameter(attempts+1, actual)
+	}
 	return nil
 }
 
+// Unalias returns the type denoted by a type alias, such as domain.Foo for
+// type Foo = domain.Foo, following aliases of aliases, or the type itself
+// when it isn't an alias. Aliases are only types of their own as of Go
+// 1.22, whose *types.Alias is matched by its Rhs method so that the
+// generator still builds with the Go versions preceding it.
+func Unalias(typ types.Type) types.Type {
+	for {
+		alias, ok := typ.(interface{ Rhs() types.Type })
+		if !ok {
+			return typ
+		}
+		typ = alias.Rhs()
+	}
+}
+
 type primitive struct {
 	goType *types.Basic
 }
 
 type namedLiteral struct {
-	named *types.Named
+	named    *types.Named
+	typeArgs []InterpretedVariable
+}
+
+type typeParamLiteral struct {
+	param *types.TypeParam
 }
 
 type functionLiteral struct {
@@ -192,9 +287,9 @@ type functionLiteral struct {
 func (f *functionLiteral) appendFunction(sig *jen.Statement) jen.Code {
 	gennedParams := make([]jen.Code, len(f.params))
 	for p := 0; p < len(f.params); p++ {
-		param := f.params[p]
+		param := f.params[p].(*named)
 		starter := jen.Id(f.paramNames[p])
-		funcParam := starter.Add(param.AsReturnType())
+		funcParam := starter.Add(param.paramType())
 		gennedParams = append(gennedParams, funcParam)
 	}
 	sig = sig.Params(gennedParams...)
@@ -218,10 +313,11 @@ type interfaceLiteral struct {
 }
 
 type declaredFunc struct {
-	m       *types.Func
-	sig     *types.Signature
-	params  []NamedVariable
-	returns []NamedVariable
+	m          *types.Func
+	sig        *types.Signature
+	params     []NamedVariable
+	returns    []NamedVariable
+	directives Directives
 }
 
 type structLiteral struct {
@@ -233,6 +329,16 @@ type sliceLiteral struct {
 	inner InterpretedVariable
 }
 
+type arrayLiteral struct {
+	kind  *types.Array
+	inner InterpretedVariable
+}
+
+type chanLiteral struct {
+	kind  *types.Chan
+	inner InterpretedVariable
+}
+
 type pointerLiteral struct {
 	inner InterpretedVariable
 }
@@ -248,4 +354,7 @@ type named struct {
 	name       string
 	inner      InterpretedVariable
 	trulyNamed bool
+	// variadic is only true for the last parameter of a variadic
+	// signature, where inner is the slice of its element type.
+	variadic bool
 }
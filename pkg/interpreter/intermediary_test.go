@@ -0,0 +1,159 @@
+package interpreter
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/dave/jennifer/jen"
+)
+
+// sourceImporter type-checks the packages of the sources by their import
+// path, which import nothing but one another, as the standard library may
+// be newer than the type checker.
+type sourceImporter struct {
+	fset    *token.FileSet
+	sources map[string]string
+	checked map[string]*types.Package
+}
+
+func (s *sourceImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := s.checked[path]; ok {
+		return pkg, nil
+	}
+	src, ok := s.sources[path]
+	if !ok {
+		return nil, fmt.Errorf("no source for %s", path)
+	}
+	file, err := parser.ParseFile(s.fset, path+".go", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: s}
+	pkg, err := conf.Check(path, s.fset, []*ast.File{file}, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.checked[path] = pkg
+	return pkg, nil
+}
+
+// lookupType type-checks the package of the sources by the path, returning
+// its type of the name.
+func lookupType(t *testing.T, sources map[string]string, path, name string) types.Type {
+	t.Helper()
+	importer := &sourceImporter{fset: token.NewFileSet(), sources: sources, checked: make(map[string]*types.Package)}
+	pkg, err := importer.Import(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := pkg.Scope().Lookup(name)
+	if obj == nil {
+		t.Fatalf("%s declares no %s", path, name)
+	}
+	return obj.Type()
+}
+
+// deriveMethods interprets the methods of the interface of the name
+// declared by the package of the path.
+func deriveMethods(t *testing.T, sources map[string]string, path, name string) []DeclaredFunction {
+	t.Helper()
+	methods, err := DeriveInterface(lookupType(t, sources, path, name).Underlying().(*types.Interface), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return methods
+}
+
+// signature renders the method as the generator declares it, along with
+// the call forwarding it.
+func signature(method DeclaredFunction) (string, string) {
+	params := make([]jen.Code, len(method.Parameters()))
+	forwarded := make([]jen.Code, len(method.Parameters()))
+	for i, p := range method.Parameters() {
+		params[i] = p.AsFunctionParam(p.Name())
+		forwarded[i] = p.NamedParameter()
+	}
+	declared := jen.Func().Id(method.FunctionName()).Params(params...).Add(method.ReturnDefinition())
+	call := jen.Id("s").Dot(method.FunctionName()).Call(forwarded...)
+	return fmt.Sprintf("%#v", declared), fmt.Sprintf("%#v", call)
+}
+
+func TestDeriveAliases(t *testing.T) {
+	sources := map[string]string{
+		"example.com/domain": `package domain
+
+type Foo struct{ Name string }
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Stringer interface{ String() string }
+`,
+		"example.com/service": `package service
+
+import "example.com/domain"
+
+type Foo = domain.Foo
+
+type Bar = Foo
+
+type Names = []string
+
+type Local struct{}
+
+type Other = Local
+
+type Counts = domain.Pair[string, int]
+
+type Number = interface{ ~int | ~float64 }
+
+type Stringer = domain.Stringer
+
+type Service interface {
+	Alias(f Foo) Foo
+	Chained(b Bar) *Bar
+	Slice(names Names) map[string]Foo
+	Local(o Other) any
+	Generic(c Counts)
+	Unnamed(Foo, Other)
+}
+
+type Box[N Number, S Stringer, A any] interface {
+	Put(n N, s S, a A)
+}
+`,
+	}
+	want := map[string]string{
+		"Alias":   "func Alias(f domain.Foo) domain.Foo",
+		"Chained": "func Chained(b domain.Foo) *domain.Foo",
+		"Slice":   "func Slice(names []string) map[string]domain.Foo",
+		"Local":   "func Local(o service.Local) interface{}",
+		"Generic": "func Generic(c domain.Pair[string, int])",
+		"Unnamed": "func Unnamed(Foo domain.Foo, Local1 service.Local)",
+	}
+	methods := deriveMethods(t, sources, "example.com/service", "Service")
+	if len(methods) != len(want) {
+		t.Fatalf("derived %d methods, want %d", len(methods), len(want))
+	}
+	for _, method := range methods {
+		if got, _ := signature(method); got != want[method.FunctionName()] {
+			t.Errorf("got %s, want %s", got, want[method.FunctionName()])
+		}
+	}
+
+	box := lookupType(t, sources, "example.com/service", "Box").(*types.Named)
+	var constraints []string
+	for _, param := range DeriveTypeParameters(box.TypeParams()) {
+		constraints = append(constraints, fmt.Sprintf("%#v", param.Constraint()))
+	}
+	if got, want := strings.Join(strings.Fields(strings.Join(constraints, " ")), " "), "interface { ~int | ~float64 } domain.Stringer any"; got != want {
+		t.Errorf("got constraints %s, want %s", got, want)
+	}
+}
@@ -31,6 +31,10 @@ func (n *namedLiteral) Name() string {
 	return n.named.Obj().Name()
 }
 
+func (t *typeParamLiteral) Name() string {
+	return t.param.Obj().Name()
+}
+
 func (p *pointerLiteral) Name() string {
 	return fmt.Sprintf("%s%s", "ptr", p.inner.Name())
 }
@@ -43,6 +47,14 @@ func (s *sliceLiteral) Name() string {
 	return fmt.Sprintf("%s%s", "slice", s.inner.Name())
 }
 
+func (a *arrayLiteral) Name() string {
+	return fmt.Sprintf("%s%s", "array", a.inner.Name())
+}
+
+func (c *chanLiteral) Name() string {
+	return fmt.Sprintf("%s%s", "chan", c.inner.Name())
+}
+
 func (s *structLiteral) Name() string {
 	return "strct"
 }
@@ -52,5 +64,8 @@ func (n *named) Name() string {
 }
 
 func (n *named) NamedParameter() jen.Code {
+	if n.variadic {
+		return jen.Id(n.name).Op("...")
+	}
 	return jen.Id(n.name)
 }
@@ -53,6 +53,14 @@ func (s *sliceLiteral) AsFunctionParam(name string) jen.Code {
 	return jen.Id(name).Index().Add(s.inner.AsReturnType())
 }
 
+func (a *arrayLiteral) AsFunctionParam(name string) jen.Code {
+	return jen.Id(name).Add(a.AsReturnType())
+}
+
+func (c *chanLiteral) AsFunctionParam(name string) jen.Code {
+	return jen.Id(name).Add(c.AsReturnType())
+}
+
 func (p *pointerLiteral) AsFunctionParam(name string) jen.Code {
 	return jen.Id(name).Op("*").Add(p.inner.AsReturnType())
 }
@@ -62,19 +70,22 @@ func (m *mapLiteral) AsFunctionParam(name string) jen.Code {
 }
 
 func (n *named) AsFunctionParam(_ string) jen.Code {
-	return jen.Id(n.name).Add(n.inner.AsReturnType())
+	return jen.Id(n.name).Add(n.paramType())
 }
 
-func (n *namedLiteral) AsFunctionParam(name string) jen.Code {
-	code := jen.Id(name)
-	obj := n.named.Obj()
-	pkg := obj.Pkg()
-	if pkg == nil {
-		return code.Id(obj.Name())
-	}
-	if !obj.Exported() {
-		return code.Id(obj.Name())
+// paramType renders the type of the parameter as declared in a signature,
+// which for a variadic parameter is ...T rather than []T.
+func (n *named) paramType() jen.Code {
+	if slice, ok := n.inner.(*sliceLiteral); ok && n.variadic {
+		return jen.Op("...").Add(slice.inner.AsReturnType())
 	}
+	return n.inner.AsReturnType()
+}
+
+func (n *namedLiteral) AsFunctionParam(name string) jen.Code {
+	return jen.Id(name).Add(n.AsReturnType())
+}
 
-	return code.Qual(pkg.Path(), obj.Name())
+func (t *typeParamLiteral) AsFunctionParam(name string) jen.Code {
+	return jen.Id(name).Id(t.Name())
 }
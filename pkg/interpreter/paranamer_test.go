@@ -0,0 +1,82 @@
+package interpreter
+
+import "testing"
+
+func TestDeriveVariadic(t *testing.T) {
+	sources := map[string]string{
+		"example.com/domain": `package domain
+
+type Key struct{}
+`,
+		"example.com/service": `package service
+
+import "example.com/domain"
+
+type Service interface {
+	Log(format string, args ...interface{})
+	Sum(nums ...int) int
+	Join(string, ...string) string
+	Put(keys ...*domain.Key) error
+	Run(fns ...func(int) error)
+	Set(values []string)
+	Apply(fn func(prefix string, rest ...string))
+}
+`,
+	}
+	tests := map[string]struct {
+		declared string
+		call     string
+		variadic bool
+	}{
+		"Log": {
+			declared: "func Log(format string, args ...interface{})",
+			call:     "s.Log(format, args...)",
+			variadic: true,
+		},
+		"Sum": {
+			declared: "func Sum(nums ...int) int",
+			call:     "s.Sum(nums...)",
+			variadic: true,
+		},
+		"Join": {
+			declared: "func Join(string string, slicestring1 ...string) string",
+			call:     "s.Join(string, slicestring1...)",
+			variadic: true,
+		},
+		"Put": {
+			declared: "func Put(keys ...*domain.Key) error",
+			call:     "s.Put(keys...)",
+			variadic: true,
+		},
+		"Run": {
+			declared: "func Run(fns ...func(int) error)",
+			call:     "s.Run(fns...)",
+			variadic: true,
+		},
+		"Apply": {
+			declared: "func Apply(fn func(prefix string, rest ...string))",
+			call:     "s.Apply(fn)",
+		},
+		"Set": {
+			declared: "func Set(values []string)",
+			call:     "s.Set(values)",
+		},
+	}
+	methods := deriveMethods(t, sources, "example.com/service", "Service")
+	if len(methods) != len(tests) {
+		t.Fatalf("derived %d methods, want %d", len(methods), len(tests))
+	}
+	for _, method := range methods {
+		test := tests[method.FunctionName()]
+		declared, call := signature(method)
+		if declared != test.declared {
+			t.Errorf("declared %s, want %s", declared, test.declared)
+		}
+		if call != test.call {
+			t.Errorf("forwarded as %s, want %s", call, test.call)
+		}
+		if method.Variadic() != test.variadic {
+			t.Errorf("%s: variadic is %t, want %t", method.FunctionName(), method.Variadic(), test.variadic)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package interpreter
 
 import (
+	"go/types"
+
 	"github.com/dave/jennifer/jen"
 )
 
@@ -11,13 +13,24 @@ func (p *primitive) AsReturnType() jen.Code {
 func (n *namedLiteral) AsReturnType() jen.Code {
 	obj := n.named.Obj()
 	pkg := obj.Pkg()
-	if pkg == nil {
-		return jen.Id(obj.Name())
+	var code *jen.Statement
+	if pkg == nil || !obj.Exported() {
+		code = jen.Id(obj.Name())
+	} else {
+		code = jen.Qual(pkg.Path(), obj.Name())
+	}
+	if len(n.typeArgs) == 0 {
+		return code
 	}
-	if !obj.Exported() {
-		return jen.Id(obj.Name())
+	typeArgs := make([]jen.Code, len(n.typeArgs))
+	for i, arg := range n.typeArgs {
+		typeArgs[i] = arg.AsReturnType()
 	}
-	return jen.Qual(pkg.Path(), obj.Name())
+	return code.Types(typeArgs...)
+}
+
+func (t *typeParamLiteral) AsReturnType() jen.Code {
+	return jen.Id(t.Name())
 }
 
 func (f *functionLiteral) AsReturnType() jen.Code {
@@ -64,6 +77,27 @@ func (s *sliceLiteral) AsReturnType() jen.Code {
 	return jen.Index().Add(s.inner.AsReturnType())
 }
 
+func (a *arrayLiteral) AsReturnType() jen.Code {
+	return jen.Index(jen.Lit(int(a.kind.Len()))).Add(a.inner.AsReturnType())
+}
+
+// AsReturnType renders the channel with its direction, where a
+// bidirectional channel of receive-only channels requires parentheses
+// as in chan (<-chan T).
+func (c *chanLiteral) AsReturnType() jen.Code {
+	elem := c.inner.AsReturnType()
+	if inner, ok := c.kind.Elem().(*types.Chan); ok && inner.Dir() == types.RecvOnly && c.kind.Dir() == types.SendRecv {
+		elem = jen.Parens(elem)
+	}
+	switch c.kind.Dir() {
+	case types.SendOnly:
+		return jen.Chan().Op("<-").Add(elem)
+	case types.RecvOnly:
+		return jen.Op("<-").Chan().Add(elem)
+	}
+	return jen.Chan().Add(elem)
+}
+
 func (p *pointerLiteral) AsReturnType() jen.Code {
 	return jen.Op("*").Add(p.inner.AsReturnType())
 }
@@ -0,0 +1,63 @@
+package interpreter
+
+import "testing"
+
+func TestDeriveChansAndArrays(t *testing.T) {
+	sources := map[string]string{
+		"example.com/domain": `package domain
+
+type Event struct{}
+`,
+		"example.com/service": `package service
+
+import "example.com/domain"
+
+type Service interface {
+	Bidirectional(events chan domain.Event) chan int
+	SendOnly(events chan<- domain.Event) chan<- int
+	ReceiveOnly(events <-chan domain.Event) <-chan int
+	ChanOfReceiveOnly(c chan (<-chan int)) chan (<-chan int)
+	ChanOfSendOnly(c chan chan<- int) chan<- chan int
+	ReceiveOnlyOfReceiveOnly(c <-chan <-chan int) chan<- <-chan int
+	Array(ids [4]string) [2][3]int
+	ArrayOfChans(c [2]<-chan *domain.Event) []chan [1]byte
+}
+`,
+	}
+	want := map[string]string{
+		"Bidirectional":            "func Bidirectional(events chan domain.Event) chan int",
+		"SendOnly":                 "func SendOnly(events chan<- domain.Event) chan<- int",
+		"ReceiveOnly":              "func ReceiveOnly(events <-chan domain.Event) <-chan int",
+		"ChanOfReceiveOnly":        "func ChanOfReceiveOnly(c chan (<-chan int)) chan (<-chan int)",
+		"ChanOfSendOnly":           "func ChanOfSendOnly(c chan chan<- int) chan<- chan int",
+		"ReceiveOnlyOfReceiveOnly": "func ReceiveOnlyOfReceiveOnly(c <-chan <-chan int) chan<- <-chan int",
+		"Array":                    "func Array(ids [4]string) [2][3]int",
+		"ArrayOfChans":             "func ArrayOfChans(c [2]<-chan *domain.Event) []chan [1]byte",
+	}
+	methods := deriveMethods(t, sources, "example.com/service", "Service")
+	if len(methods) != len(want) {
+		t.Fatalf("derived %d methods, want %d", len(methods), len(want))
+	}
+	for _, method := range methods {
+		if got, _ := signature(method); got != want[method.FunctionName()] {
+			t.Errorf("got %s, want %s", got, want[method.FunctionName()])
+		}
+	}
+}
+
+func TestChanAndArrayNames(t *testing.T) {
+	sources := map[string]string{
+		"example.com/service": `package service
+
+type Service interface {
+	Unnamed(chan<- int, [3]string, <-chan []bool)
+}
+`,
+	}
+	methods := deriveMethods(t, sources, "example.com/service", "Service")
+	got, _ := signature(methods[0])
+	want := "func Unnamed(chanint chan<- int, arraystring1 [3]string, chanslicebool2 <-chan []bool)"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
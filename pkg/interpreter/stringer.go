@@ -15,11 +15,11 @@ func (p *primitive) Stringer(name string) jen.Code {
 	basic := p.goType.Kind()
 	switch basic {
 	case types.Invalid:
-		return jen.Lit(`"undefined"`)
+		return jen.Lit("undefined")
 	case types.Bool, types.UntypedBool:
-		return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+		return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 	case types.UntypedRune:
-		return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%c"`), jen.Lit(name))
+		return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%c"), jen.Id(name))
 	case types.Int, types.Int8,
 		types.Int16, types.Int32,
 		types.Int64, types.Uint,
@@ -29,11 +29,13 @@ func (p *primitive) Stringer(name string) jen.Code {
 		types.Complex64, types.Complex128,
 		types.UntypedInt, types.UntypedFloat,
 		types.UntypedComplex, types.Uintptr, types.UnsafePointer:
-		return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
-	case types.String, types.UntypedString, types.UntypedNil:
-		return jen.Lit(name)
+		return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
+	case types.String, types.UntypedString:
+		return jen.Id(name)
+	case types.UntypedNil:
+		return jen.Lit("nil")
 	}
-	return jen.Lit(`"undefined"`)
+	return jen.Lit("undefined")
 }
 
 func (f *functionLiteral) DebugString() string {
@@ -41,7 +43,8 @@ func (f *functionLiteral) DebugString() string {
 }
 
 func (f *functionLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	// a func value can't be printed meaningfully, so we settle for its type
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%T"), jen.Id(name))
 }
 
 func (i *interfaceLiteral) DebugString() string {
@@ -49,7 +52,7 @@ func (i *interfaceLiteral) DebugString() string {
 }
 
 func (i *interfaceLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (n *namedLiteral) DebugString() string {
@@ -57,7 +60,18 @@ func (n *namedLiteral) DebugString() string {
 }
 
 func (n *namedLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	if _, ok := n.named.Underlying().(*types.Signature); ok {
+		return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%T"), jen.Id(name))
+	}
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
+}
+
+func (t *typeParamLiteral) DebugString() string {
+	return fmt.Sprintf("Type parameter: %s %s", t.param.String(), t.param.Constraint().String())
+}
+
+func (t *typeParamLiteral) Stringer(name string) jen.Code {
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (d *declaredFunc) DebugString() string {
@@ -65,7 +79,7 @@ func (d *declaredFunc) DebugString() string {
 }
 
 func (d *declaredFunc) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (s *structLiteral) DebugString() string {
@@ -73,7 +87,7 @@ func (s *structLiteral) DebugString() string {
 }
 
 func (s *structLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (s *sliceLiteral) DebugString() string {
@@ -81,7 +95,23 @@ func (s *sliceLiteral) DebugString() string {
 }
 
 func (s *sliceLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
+}
+
+func (a *arrayLiteral) DebugString() string {
+	return fmt.Sprintf("Array type: %s", a.kind.String())
+}
+
+func (a *arrayLiteral) Stringer(name string) jen.Code {
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
+}
+
+func (c *chanLiteral) DebugString() string {
+	return fmt.Sprintf("Chan type: %s", c.kind.String())
+}
+
+func (c *chanLiteral) Stringer(name string) jen.Code {
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (p *pointerLiteral) DebugString() string {
@@ -89,7 +119,7 @@ func (p *pointerLiteral) DebugString() string {
 }
 
 func (p *pointerLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (m *mapLiteral) DebugString() string {
@@ -97,7 +127,7 @@ func (m *mapLiteral) DebugString() string {
 }
 
 func (m *mapLiteral) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return jen.Qual("fmt", "Sprintf").Call(jen.Lit("%v"), jen.Id(name))
 }
 
 func (n *named) DebugString() string {
@@ -105,5 +135,5 @@ func (n *named) DebugString() string {
 }
 
 func (n *named) Stringer(name string) jen.Code {
-	return jen.Qual("fmt", "Sprintf").Params(jen.Lit(`"%v"`), jen.Lit(name))
+	return n.inner.Stringer(name)
 }
@@ -12,6 +12,10 @@ func (n *namedLiteral) UnderlyingType() types.Type {
 	return n.named
 }
 
+func (t *typeParamLiteral) UnderlyingType() types.Type {
+	return t.param
+}
+
 func (f *functionLiteral) UnderlyingType() types.Type {
 	return f.sig
 }
@@ -32,6 +36,14 @@ func (s *sliceLiteral) UnderlyingType() types.Type {
 	return s.inner.UnderlyingType()
 }
 
+func (a *arrayLiteral) UnderlyingType() types.Type {
+	return a.kind
+}
+
+func (c *chanLiteral) UnderlyingType() types.Type {
+	return c.kind
+}
+
 func (p *pointerLiteral) UnderlyingType() types.Type {
 	return p.inner.UnderlyingType()
 }
@@ -0,0 +1,181 @@
+// Package plugin defines the protocol of external plugins, which generate
+// middleware without being compiled into the generator, along with an SDK
+// for writing them.
+//
+// An external plugin is an executable named middleware-gen-<name> found on
+// the PATH, selected as any other plugin by -plugin <name>. For each
+// interface, the generator runs it once, writing a Request as JSON to its
+// stdin and reading a Response as JSON from its stdout, much like protoc
+// plugins. A plugin failing with a non-zero exit code has its stderr
+// reported.
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is the version of the protocol, which changes when a Request
+// can no longer be read by the plugins of a previous version.
+const Version = 1
+
+// Request describes the middleware to generate for an interface.
+type Request struct {
+	Version int `json:"version"`
+	// Name is the name the plugin was selected by.
+	Name string `json:"name"`
+	// Options are those given after the name, as in name:key=value.
+	Options map[string]string `json:"options,omitempty"`
+	Service Service           `json:"service"`
+	// Methods are the methods to generate, leaving out those skipping the
+	// plugin by //middleware:skip, which are forwarded untouched.
+	Methods []Method `json:"methods"`
+	// Imports are the names of the packages referenced by the types of the
+	// Methods by their import path, e.g. "context": "context". Packages
+	// sharing a name are told apart by a numbered alias, e.g. v1 and v12.
+	Imports map[string]string `json:"imports,omitempty"`
+}
+
+// Service describes the generated struct wrapping the interface.
+type Service struct {
+	// TypeName is the name of the interface, e.g. Repository.
+	TypeName string `json:"typeName"`
+	// Package is the name of the package being generated into.
+	Package string `json:"package"`
+	// StructName is the name of the generated struct, e.g. auditRepository.
+	StructName string `json:"structName"`
+	// StructPtr is the receiver of the generated methods.
+	StructPtr string `json:"structPtr"`
+	// ServicePtr is the field of the struct holding the wrapped interface.
+	ServicePtr string `json:"servicePtr"`
+}
+
+// Method describes a method of the interface.
+type Method struct {
+	Name string `json:"name"`
+	// Params are the parameters of the method, the last of which is passed
+	// as a slice when the method is Variadic.
+	Params   []Variable `json:"params"`
+	Variadic bool       `json:"variadic,omitempty"`
+	// Results are the results of the method, named by local variables that
+	// don't collide with the Params, a trailing error preferably being err.
+	Results []Variable `json:"results"`
+	// Context is the name of the first context.Context parameter, if any.
+	Context string `json:"context,omitempty"`
+	// HasError is set when the last of the Results is an error.
+	HasError bool `json:"hasError,omitempty"`
+	// Call forwards the call to the wrapped interface, e.g.
+	// a.r.Find(ctx, id).
+	Call string `json:"call"`
+	// Directives are the //middleware: comments in the doc of the method.
+	Directives []Directive `json:"directives,omitempty"`
+}
+
+// Variable is a parameter or result of a Method, whose Type is qualified
+// by the package names of the Request's Imports, e.g. []store.User.
+type Variable struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Directive is a //middleware:<name> <args> comment, as in
+// //middleware:redact password.
+type Directive struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+// Response is the middleware generated by a plugin.
+type Response struct {
+	// Error fails the generation when set.
+	Error string `json:"error,omitempty"`
+	// FileNamePrefix names the generated file <prefix>_<type>.go, which
+	// defaults to the name of the plugin.
+	FileNamePrefix string `json:"fileNamePrefix,omitempty"`
+	// FactorySuffix names the factory New<type><suffix>, which defaults to
+	// the name of the plugin capitalized.
+	FactorySuffix string `json:"factorySuffix,omitempty"`
+	// Parameters are the parameters of the factory.
+	Parameters []Parameter `json:"parameters,omitempty"`
+	// Fields are additional fields of the struct, assigned from the local
+	// variables of the same name declared by the Factory.
+	Fields []Parameter `json:"fields,omitempty"`
+	// Factory holds the statements of the factory run before the middleware
+	// is returned.
+	Factory string `json:"factory,omitempty"`
+	// Imports are the names the packages referenced by the Factory and the
+	// Methods are imported as by their import path, which must not name
+	// another package of the Request's Imports. The selectors of these
+	// names, e.g. slog.String, are qualified in the generated file, unless
+	// a parameter, result or local variable shadows the name.
+	Imports map[string]string `json:"imports,omitempty"`
+	// Methods hold the statements of the body of each method by its name.
+	// A method without a body forwards the call untouched.
+	Methods map[string]string `json:"methods,omitempty"`
+}
+
+// Parameter is a parameter of the factory, stored on the struct in Field
+// when it is set.
+type Parameter struct {
+	Name string `json:"name"`
+	// Type is the name of the type, qualified by Path when set.
+	Type    string `json:"type"`
+	Path    string `json:"path,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Pointer bool   `json:"pointer,omitempty"`
+}
+
+// LocalName suffixes the desired name of a local variable until it no
+// longer collides with any of the Params.
+func (m Method) LocalName(desired string) string {
+	name := desired
+	for i := 1; m.hasParam(name); i++ {
+		name = fmt.Sprintf("%s%d", desired, i)
+	}
+	return name
+}
+
+func (m Method) hasParam(name string) bool {
+	for _, p := range m.Params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasContext reports whether the method accepts a context.Context.
+func (m Method) HasContext() bool {
+	return m.Context != ""
+}
+
+// ResultNames joins the names of the Results, as in r0, err.
+func (m Method) ResultNames() string {
+	names := make([]string, len(m.Results))
+	for i, r := range m.Results {
+		names[i] = r.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Forward forwards the Call, returning its results if any.
+func (m Method) Forward() string {
+	if len(m.Results) > 0 {
+		return "return " + m.Call
+	}
+	return m.Call
+}
+
+// Directive returns the arguments of every directive by the name, reporting
+// whether the method carries any.
+func (m Method) Directive(name string) ([]string, bool) {
+	var args []string
+	found := false
+	for _, d := range m.Directives {
+		if d.Name == name {
+			args = append(args, d.Args...)
+			found = true
+		}
+	}
+	return args, found
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Handler generates the middleware of the interface described by a Request.
+type Handler interface {
+	Generate(req *Request) (*Response, error)
+}
+
+// HandlerFunc adapts a function to a Handler.
+type HandlerFunc func(req *Request) (*Response, error)
+
+func (f HandlerFunc) Generate(req *Request) (*Response, error) {
+	return f(req)
+}
+
+// MethodFunc adapts a function generating the body of each method to a
+// Handler, declaring the factory parameters, struct fields and imports
+// of the given Response. A method for which the function returns an empty
+// body forwards the call untouched. For example, a plugin auditing every
+// call may be written as:
+//
+//	func main() {
+//		plugin.Main(plugin.MethodFunc(plugin.Response{
+//			Parameters: []plugin.Parameter{{Name: "logger", Type: "Logger", Path: "log/slog", Field: "lg", Pointer: true}},
+//			Imports:    map[string]string{"log/slog": "slog"},
+//		}, func(req *plugin.Request, m plugin.Method) (string, error) {
+//			return fmt.Sprintf("%s.lg.Info(%q)\n%s", req.Service.StructPtr, m.Name, m.Forward()), nil
+//		}))
+//	}
+func MethodFunc(base Response, body func(req *Request, m Method) (string, error)) Handler {
+	return HandlerFunc(func(req *Request) (*Response, error) {
+		resp := base
+		resp.Methods = make(map[string]string, len(req.Methods))
+		for _, m := range req.Methods {
+			generated, err := body(req, m)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", m.Name, err)
+			}
+			if generated != "" {
+				resp.Methods[m.Name] = generated
+			}
+		}
+		return &resp, nil
+	})
+}
+
+// Main runs the plugin on the Request read from stdin, writing its Response
+// to stdout. It exits with 1 when the Request can't be read, or the
+// Response can't be written.
+func Main(h Handler) {
+	if err := Run(os.Stdin, os.Stdout, h); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run reads a Request from r and writes the Response of the Handler to w.
+// An error of the Handler is written as the Error of the Response.
+func Run(r io.Reader, w io.Writer, h Handler) error {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("reading request: %w", err)
+	}
+	if req.Version != Version {
+		return fmt.Errorf("unsupported protocol version %d, expected %d", req.Version, Version)
+	}
+	resp, err := h.Generate(&req)
+	if err != nil {
+		resp = &Response{Error: err.Error()}
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return fmt.Errorf("writing response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package plugin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gabizou/middleware-generator/pkg/plugin"
+)
+
+func TestRun(t *testing.T) {
+	handler := plugin.MethodFunc(plugin.Response{
+		Parameters: []plugin.Parameter{{Name: "logger", Type: "Logger", Path: "log/slog", Field: "lg", Pointer: true}},
+		Imports:    map[string]string{"log/slog": "slog"},
+	}, func(req *plugin.Request, m plugin.Method) (string, error) {
+		switch m.Name {
+		case "Ping":
+			return "", nil
+		case "Fail":
+			return "", fmt.Errorf("unsupported")
+		}
+		return fmt.Sprintf("%s.lg.Info(%q)\n%s", req.Service.StructPtr, m.Name, m.Forward()), nil
+	})
+	find := plugin.Method{
+		Name:    "Find",
+		Params:  []plugin.Variable{{Name: "id", Type: "string"}},
+		Results: []plugin.Variable{{Name: "r0", Type: "store.User"}, {Name: "err", Type: "error"}},
+		Call:    "a.s.Find(id)",
+	}
+	ping := plugin.Method{Name: "Ping", Call: "a.s.Ping()"}
+	request := func(version int, methods ...plugin.Method) plugin.Request {
+		return plugin.Request{
+			Version: version,
+			Name:    "audit",
+			Service: plugin.Service{TypeName: "Service", Package: "service", StructName: "auditService", StructPtr: "a", ServicePtr: "s"},
+			Methods: methods,
+			Imports: map[string]string{"example.com/store": "store"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		req     plugin.Request
+		want    plugin.Response
+		wantErr string
+	}{
+		{
+			name: "methods",
+			req:  request(plugin.Version, find, ping),
+			want: plugin.Response{
+				Parameters: []plugin.Parameter{{Name: "logger", Type: "Logger", Path: "log/slog", Field: "lg", Pointer: true}},
+				Imports:    map[string]string{"log/slog": "slog"},
+				Methods:    map[string]string{"Find": "a.lg.Info(\"Find\")\nreturn a.s.Find(id)"},
+			},
+		},
+		{
+			name: "error of the handler",
+			req:  request(plugin.Version, find, plugin.Method{Name: "Fail"}),
+			want: plugin.Response{Error: "Fail: unsupported"},
+		},
+		{
+			name:    "unsupported version",
+			req:     request(plugin.Version + 1),
+			wantErr: fmt.Sprintf("unsupported protocol version %d, expected %d", plugin.Version+1, plugin.Version),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in, err := json.Marshal(test.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err = plugin.Run(bytes.NewReader(in), &out, handler)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("got %v, want %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resp plugin.Response
+			if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp, test.want) {
+				t.Errorf("got %+v, want %+v", resp, test.want)
+			}
+		})
+	}
+}
+
+func TestRunMalformedRequest(t *testing.T) {
+	err := plugin.Run(strings.NewReader("{"), &bytes.Buffer{}, plugin.HandlerFunc(func(req *plugin.Request) (*plugin.Response, error) {
+		t.Fatal("the handler ran")
+		return nil, nil
+	}))
+	if err == nil || !strings.HasPrefix(err.Error(), "reading request: ") {
+		t.Errorf("got %v, want an error reading the request", err)
+	}
+}
+
+func TestMethodLocalName(t *testing.T) {
+	m := plugin.Method{Params: []plugin.Variable{{Name: "start"}, {Name: "start1"}, {Name: "ctx"}}}
+	for desired, want := range map[string]string{"start": "start2", "ctx": "ctx1", "err": "err"} {
+		if got := m.LocalName(desired); got != want {
+			t.Errorf("LocalName(%q) = %q, want %q", desired, got, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"github.com/gabizou/middleware-generator/pkg/generator"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const (
+	_slogPath = "log/slog"
+	_timePath = "time"
+	_ctxPath  = "context"
+)
+
+func init() { //nolint:gochecknoinits
+	generator.Register("logger", logger{})
+}
+
+type logger struct {
+}
+
+func (l logger) FileNamePrefix() string {
+	return "logger"
+}
+
+func (l logger) FactorySuffix() string {
+	return "Logger"
+}
+
+func (l logger) ConfigureModel(model *generator.ServiceModel) {
+	model.StructPrefix = "logger%s"
+	model.InputParameters = []generator.MiddlewareParameter{
+		{
+			VariableName: "logger",
+			TypeName:     "Logger",
+			TypePath:     _slogPath,
+			FieldName:    "lg",
+			Pointer:      true,
+		},
+	}
+}
+
+// GenerateFunctionImplementation generates a function that logs the
+// method name along with each of its parameters before forwarding the
+// call, then logs the elapsed time once the call returns. A returned
+// error is logged at slog.LevelError instead of slog.LevelDebug. The
+// parameters listed by //middleware:redact are logged as [REDACTED]. The
+// parameters are only formatted when the logger is enabled for
+// slog.LevelDebug, sparing the cost of formatting them at higher levels.
+func (l logger) GenerateFunctionImplementation(
+	builder *jen.Statement,
+	service *generator.ServiceModel,
+	method interpreter.DeclaredFunction,
+) jen.Code {
+	ctx := jen.Qual(_ctxPath, "Background").Call()
+	ctxParam := method.ContextParameter()
+	if ctxParam != nil {
+		ctx = jen.Id(ctxParam.Name())
+	}
+	startName := method.LocalName("start")
+	logger := jen.Id(service.StructPtr).Dot("lg")
+	methodAttr := jen.Qual(_slogPath, "String").Call(jen.Lit("method"), jen.Lit(method.Name()))
+	durationAttr := jen.Qual(_slogPath, "Duration").Call(
+		jen.Lit("duration"),
+		jen.Qual(_timePath, "Since").Call(jen.Id(startName)),
+	)
+
+	entryAttrs := []jen.Code{ctx, jen.Qual(_slogPath, "LevelDebug"), jen.Lit("calling method"), methodAttr}
+	formatted := false
+	for _, p := range method.Parameters() {
+		if p == ctxParam {
+			continue
+		}
+		value := p.Stringer(p.Name())
+		if method.Directives().Contains("redact", p.Name()) {
+			value = jen.Lit("[REDACTED]")
+		}
+		entryAttrs = append(entryAttrs, jen.Qual(_slogPath, "String").Call(jen.Lit(p.Name()), value))
+		formatted = true
+	}
+	entryLog := logger.Clone().Dot("LogAttrs").Call(entryAttrs...)
+	if formatted {
+		/* code to generate
+		if ${service.StructPtr}.lg.Enabled($ctx, slog.LevelDebug) {
+		  ${service.StructPtr}.lg.LogAttrs($ctx, slog.LevelDebug, "calling method", slog.String("method", ${DeclaredFunction.Name}), ...)
+		}
+		*/
+		entryLog = jen.If(logger.Clone().Dot("Enabled").Call(ctx, jen.Qual(_slogPath, "LevelDebug"))).Block(entryLog)
+	}
+	/* code to generate
+	start := time.Now()
+	*/
+	lines := []jen.Code{
+		entryLog,
+		jen.Id(startName).Op(":=").Qual(_timePath, "Now").Call(),
+	}
+
+	results := method.ResultNames()
+	resultIds := make([]jen.Code, len(results))
+	for i, r := range results {
+		resultIds[i] = jen.Id(r)
+	}
+	call := service.ForwardCall(method)
+	if len(results) > 0 {
+		call = jen.List(resultIds...).Op(":=").Add(call)
+	}
+	lines = append(lines, call)
+
+	exitLog := func(level string, msg string, attrs ...jen.Code) jen.Code {
+		params := append([]jen.Code{ctx, jen.Qual(_slogPath, level), jen.Lit(msg), methodAttr, durationAttr}, attrs...)
+		return logger.Clone().Dot("LogAttrs").Call(params...)
+	}
+	if method.ReturnsError() {
+		errName := results[len(results)-1]
+		/* code to generate
+		if err != nil {
+		  ${service.StructPtr}.lg.LogAttrs($ctx, slog.LevelError, "method failed", ..., slog.Any("error", err))
+		} else {
+		  ${service.StructPtr}.lg.LogAttrs($ctx, slog.LevelDebug, "method finished", ...)
+		}
+		*/
+		lines = append(lines,
+			jen.If(jen.Id(errName).Op("!=").Nil()).Block(
+				exitLog("LevelError", "method failed", jen.Qual(_slogPath, "Any").Call(jen.Lit("error"), jen.Id(errName))),
+			).Else().Block(
+				exitLog("LevelDebug", "method finished"),
+			),
+		)
+	} else {
+		lines = append(lines, exitLog("LevelDebug", "method finished"))
+	}
+
+	if len(results) > 0 {
+		lines = append(lines, jen.Return(resultIds...))
+	}
+
+	return builder.Block(lines...)
+}
+
+func (l logger) GetRequiredImportNames() map[string]string {
+	return map[string]string{
+		_slogPath: "slog",
+		_timePath: "time",
+		_ctxPath:  "context",
+	}
+}
@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"github.com/gabizou/middleware-generator/pkg/generator"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const (
+	_prometheusPath = "github.com/prometheus/client_golang/prometheus"
+	_timePath       = "time"
+)
+
+func init() { //nolint:gochecknoinits
+	generator.Register("metrics", metrics{})
+}
+
+type metrics struct {
+}
+
+func (m metrics) FileNamePrefix() string {
+	return "metrics"
+}
+
+func (m metrics) FactorySuffix() string {
+	return "Metrics"
+}
+
+func (m metrics) ConfigureModel(model *generator.ServiceModel) {
+	model.StructPrefix = "metrics%s"
+	model.InputParameters = []generator.MiddlewareParameter{
+		{
+			VariableName: "registerer",
+			TypeName:     "Registerer",
+			TypePath:     _prometheusPath,
+		},
+		{
+			VariableName: "namespace",
+			TypeName:     "string",
+		},
+		{
+			VariableName: "subsystem",
+			TypeName:     "string",
+		},
+	}
+	model.StructFields = []generator.MiddlewareParameter{
+		{
+			VariableName: "requests",
+			TypeName:     "CounterVec",
+			TypePath:     _prometheusPath,
+			FieldName:    "requests",
+			Pointer:      true,
+		},
+		{
+			VariableName: "failures",
+			TypeName:     "CounterVec",
+			TypePath:     _prometheusPath,
+			FieldName:    "failures",
+			Pointer:      true,
+		},
+		{
+			VariableName: "duration",
+			TypeName:     "HistogramVec",
+			TypePath:     _prometheusPath,
+			FieldName:    "duration",
+			Pointer:      true,
+		},
+	}
+}
+
+// GenerateFactoryImplementation creates the collectors, all labeled by
+// method name along with the constant service label naming the interface,
+// and registers them with the prometheus.Registerer passed to the factory.
+// The interfaces sharing a namespace and subsystem thus report distinct
+// series, e.g. service="Repository",method="Find". Collectors already
+// registered by a previous call for the same interface, namespace and
+// subsystem are shared instead, so that the factory may be called again,
+// e.g. to wrap another instance of the service. Only a collector
+// conflicting with another one panics, as with prometheus.MustRegister.
+func (m metrics) GenerateFactoryImplementation(group *jen.Group, service *generator.ServiceModel) {
+	opts := func(name, help string) jen.Dict {
+		return jen.Dict{
+			jen.Id("Namespace"):   jen.Id("namespace"),
+			jen.Id("Subsystem"):   jen.Id("subsystem"),
+			jen.Id("Name"):        jen.Lit(name),
+			jen.Id("Help"):        jen.Lit(help),
+			jen.Id("ConstLabels"): jen.Qual(_prometheusPath, "Labels").Values(jen.Dict{jen.Lit("service"): jen.Lit(service.TypeName)}),
+		}
+	}
+	labels := jen.Index().String().Values(jen.Lit("method"))
+	/* code to generate
+	register := func(collector prometheus.Collector) prometheus.Collector {
+	  if err := registerer.Register(collector); err != nil {
+	    registered, ok := err.(prometheus.AlreadyRegisteredError)
+	    if !ok {
+	      panic(err)
+	    }
+	    return registered.ExistingCollector
+	  }
+	  return collector
+	}
+	*/
+	group.Id("register").Op(":=").Func().
+		Params(jen.Id("collector").Qual(_prometheusPath, "Collector")).
+		Qual(_prometheusPath, "Collector").
+		Block(
+			jen.If(
+				jen.Err().Op(":=").Id("registerer").Dot("Register").Call(jen.Id("collector")),
+				jen.Err().Op("!=").Nil(),
+			).Block(
+				jen.List(jen.Id("registered"), jen.Id("ok")).Op(":=").
+					Err().Assert(jen.Qual(_prometheusPath, "AlreadyRegisteredError")),
+				jen.If(jen.Op("!").Id("ok")).Block(jen.Panic(jen.Err())),
+				jen.Return(jen.Id("registered").Dot("ExistingCollector")),
+			),
+			jen.Return(jen.Id("collector")),
+		)
+	/* code to generate
+	requests := register(prometheus.NewCounterVec(prometheus.CounterOpts{...}, []string{"method"})).(*prometheus.CounterVec)
+	failures := register(prometheus.NewCounterVec(prometheus.CounterOpts{...}, []string{"method"})).(*prometheus.CounterVec)
+	duration := register(prometheus.NewHistogramVec(prometheus.HistogramOpts{...}, []string{"method"})).(*prometheus.HistogramVec)
+	*/
+	registered := func(name, constructor, optsType, collector string, values jen.Dict) {
+		group.Id(name).Op(":=").Id("register").Call(
+			jen.Qual(_prometheusPath, constructor).Call(jen.Qual(_prometheusPath, optsType).Values(values), labels),
+		).Assert(jen.Op("*").Qual(_prometheusPath, collector))
+	}
+	registered("requests", "NewCounterVec", "CounterOpts", "CounterVec",
+		opts("requests_total", "Total number of calls by method."))
+	registered("failures", "NewCounterVec", "CounterOpts", "CounterVec",
+		opts("errors_total", "Total number of calls by method that returned an error."))
+	registered("duration", "NewHistogramVec", "HistogramOpts", "HistogramVec",
+		opts("request_duration_seconds", "Duration of calls by method in seconds."))
+}
+
+// GenerateFunctionImplementation generates a function that counts each call
+// and observes its duration, counting a failure as well when the method's
+// trailing error result is non-nil.
+func (m metrics) GenerateFunctionImplementation(
+	builder *jen.Statement,
+	service *generator.ServiceModel,
+	method interpreter.DeclaredFunction,
+) jen.Code {
+	startName := method.LocalName("start")
+	label := jen.Lit(method.Name())
+	field := func(name string) *jen.Statement {
+		return jen.Id(service.StructPtr).Dot(name)
+	}
+	/* code to generate
+	start := time.Now()
+	*/
+	lines := []jen.Code{
+		jen.Id(startName).Op(":=").Qual(_timePath, "Now").Call(),
+	}
+
+	results := method.ResultNames()
+	resultIds := make([]jen.Code, len(results))
+	for i, r := range results {
+		resultIds[i] = jen.Id(r)
+	}
+	call := service.ForwardCall(method)
+	if len(results) > 0 {
+		call = jen.List(resultIds...).Op(":=").Add(call)
+	}
+	/* code to generate
+	r0, err := ${service.StructPtr}.${service.ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+	${service.StructPtr}.requests.WithLabelValues(${DeclaredFunction.Name}).Inc()
+	*/
+	lines = append(lines,
+		call,
+		field("requests").Dot("WithLabelValues").Call(label).Dot("Inc").Call(),
+	)
+	if method.ReturnsError() {
+		/* code to generate
+		if err != nil {
+		  ${service.StructPtr}.failures.WithLabelValues(${DeclaredFunction.Name}).Inc()
+		}
+		*/
+		lines = append(lines, jen.If(jen.Id(results[len(results)-1]).Op("!=").Nil()).Block(
+			field("failures").Dot("WithLabelValues").Call(label).Dot("Inc").Call(),
+		))
+	}
+	/* code to generate
+	${service.StructPtr}.duration.WithLabelValues(${DeclaredFunction.Name}).Observe(time.Since(start).Seconds())
+	*/
+	lines = append(lines, field("duration").Dot("WithLabelValues").Call(label).Dot("Observe").Call(
+		jen.Qual(_timePath, "Since").Call(jen.Id(startName)).Dot("Seconds").Call(),
+	))
+
+	if len(results) > 0 {
+		lines = append(lines, jen.Return(resultIds...))
+	}
+
+	return builder.Block(lines...)
+}
+
+func (m metrics) GetRequiredImportNames() map[string]string {
+	return map[string]string{
+		_prometheusPath: "prometheus",
+		_timePath:       "time",
+	}
+}
@@ -0,0 +1,173 @@
+package otel
+
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/gabizou/middleware-generator/pkg/generator"
+	"github.com/gabizou/middleware-generator/pkg/interpreter"
+
+	"github.com/dave/jennifer/jen"
+)
+
+const (
+	_tracePath     = "go.opentelemetry.io/otel/trace"
+	_attributePath = "go.opentelemetry.io/otel/attribute"
+	_codesPath     = "go.opentelemetry.io/otel/codes"
+	_strconvPath   = "strconv"
+)
+
+func init() { //nolint:gochecknoinits
+	generator.Register("otel", otel{})
+}
+
+type otel struct {
+}
+
+func (o otel) FileNamePrefix() string {
+	return "otel"
+}
+
+func (o otel) FactorySuffix() string {
+	return "Otel"
+}
+
+func (o otel) ConfigureModel(model *generator.ServiceModel) {
+	model.StructPrefix = "otel%s"
+	model.InputParameters = []generator.MiddlewareParameter{
+		{
+			VariableName: "tracer",
+			TypeName:     "Tracer",
+			TypePath:     _tracePath,
+			FieldName:    "tr",
+		},
+	}
+}
+
+// GenerateFunctionImplementation generates a function that, when the
+// method has a context.Context parameter, starts a span named
+// ${ServiceModel.TypeName}.${DeclaredFunction.Name} carrying the primitive
+// parameters as attributes, and records a returned error on the span.
+// Methods without a context.Context are forwarded along untouched.
+// The span name may be set by //middleware:name, while the parameters
+// listed by //middleware:redact are not recorded.
+func (o otel) GenerateFunctionImplementation(
+	builder *jen.Statement,
+	service *generator.ServiceModel,
+	method interpreter.DeclaredFunction,
+) jen.Code {
+	ctxParam := method.ContextParameter()
+	if ctxParam == nil {
+		call := service.ForwardCall(method)
+		if len(method.Returns()) > 0 {
+			return builder.Block(jen.Return(call))
+		}
+		return builder.Block(call)
+	}
+	ctxName := ctxParam.Name()
+	spanName := method.LocalName("span")
+
+	name, ok := method.Directives().Value("name")
+	if !ok {
+		name = fmt.Sprintf("%s.%s", service.TypeName, method.Name())
+	}
+	startArgs := []jen.Code{jen.Id(ctxName), jen.Lit(name)}
+	var attributes []jen.Code
+	for _, p := range method.Parameters() {
+		if method.Directives().Contains("redact", p.Name()) {
+			continue
+		}
+		if attr := attributeOf(p); attr != nil {
+			attributes = append(attributes, attr)
+		}
+	}
+	if len(attributes) > 0 {
+		startArgs = append(startArgs, jen.Qual(_tracePath, "WithAttributes").Call(attributes...))
+	}
+	/* code to generate
+	$ctxName, span := ${service.StructPtr}.tr.Start($ctxName, "${ServiceModel.TypeName}.${DeclaredFunction.Name}", trace.WithAttributes(...))
+	defer span.End()
+	*/
+	lines := []jen.Code{
+		jen.List(jen.Id(ctxName), jen.Id(spanName)).Op(":=").
+			Id(service.StructPtr).Dot("tr").Dot("Start").Call(startArgs...),
+		jen.Defer().Id(spanName).Dot("End").Call(),
+	}
+
+	if !method.ReturnsError() {
+		call := service.ForwardCall(method)
+		if len(method.Returns()) > 0 {
+			call = jen.Return(call)
+		}
+		return builder.Block(append(lines, call)...)
+	}
+
+	results := method.ResultNames()
+	resultIds := make([]jen.Code, len(results))
+	for i, r := range results {
+		resultIds[i] = jen.Id(r)
+	}
+	errName := results[len(results)-1]
+	/* code to generate
+	r0, err := ${service.StructPtr}.${service.ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+	if err != nil {
+	  span.RecordError(err)
+	  span.SetStatus(codes.Error, err.Error())
+	}
+	return r0, err
+	*/
+	lines = append(lines,
+		jen.List(resultIds...).Op(":=").Add(service.ForwardCall(method)),
+		jen.If(jen.Id(errName).Op("!=").Nil()).Block(
+			jen.Id(spanName).Dot("RecordError").Call(jen.Id(errName)),
+			jen.Id(spanName).Dot("SetStatus").Call(jen.Qual(_codesPath, "Error"), jen.Id(errName).Dot("Error").Call()),
+		),
+		jen.Return(resultIds...),
+	)
+	return builder.Block(lines...)
+}
+
+// attributeOf creates the attribute.KeyValue for a parameter whose type is,
+// or is defined by, a basic type. Other parameters are not recorded and
+// result in nil.
+func attributeOf(p interpreter.NamedVariable) jen.Code {
+	typ := p.UnderlyingType()
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return nil
+	}
+	value := func(target types.BasicKind) jen.Code {
+		if types.Identical(typ, types.Typ[target]) {
+			return jen.Id(p.Name())
+		}
+		return jen.Id(types.Typ[target].Name()).Call(jen.Id(p.Name()))
+	}
+	key := jen.Lit(p.Name())
+	switch basic.Kind() {
+	case types.String:
+		return jen.Qual(_attributePath, "String").Call(key, value(types.String))
+	case types.Bool:
+		return jen.Qual(_attributePath, "Bool").Call(key, value(types.Bool))
+	case types.Int:
+		return jen.Qual(_attributePath, "Int").Call(key, value(types.Int))
+	case types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint8, types.Uint16, types.Uint32:
+		return jen.Qual(_attributePath, "Int64").Call(key, value(types.Int64))
+	case types.Uint, types.Uint64, types.Uintptr:
+		// may overflow an int64, so these are recorded as their decimal form
+		return jen.Qual(_attributePath, "String").Call(key,
+			jen.Qual(_strconvPath, "FormatUint").Call(value(types.Uint64), jen.Lit(10)))
+	case types.Float32, types.Float64:
+		return jen.Qual(_attributePath, "Float64").Call(key, value(types.Float64))
+	}
+	return nil
+}
+
+func (o otel) GetRequiredImportNames() map[string]string {
+	return map[string]string{
+		_tracePath:     "trace",
+		_attributePath: "attribute",
+		_codesPath:     "codes",
+		_strconvPath:   "strconv",
+	}
+}
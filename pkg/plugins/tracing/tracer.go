@@ -1,7 +1,9 @@
 package tracing
 
 import (
-	"go/types"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/gabizou/middleware-generator/pkg/generator"
 	"github.com/gabizou/middleware-generator/pkg/interpreter"
@@ -13,7 +15,58 @@ func init() { //nolint:gochecknoinits
 	generator.Register("tracer", tracer{})
 }
 
+// tracer accepts the following options:
+//   - root: true to start a root span with Tracer.StartSpan for every
+//     method without a context.Context parameter, or a +-separated list of
+//     such method names, e.g. root=Close+Stats
+//   - name: the span name, where {interface} and {method} are replaced,
+//     e.g. name={interface}.{method}. Defaults to {method}, while a method
+//     may name its span with //middleware:name repo.find instead.
 type tracer struct {
+	allRoots    bool
+	rootMethods map[string]bool
+	spanName    string
+}
+
+func (t tracer) WithOptions(options map[string]string) (generator.Customizer, error) {
+	for key, value := range options {
+		switch key {
+		case "root":
+			t.allRoots, t.rootMethods = false, nil
+			if parsed, err := strconv.ParseBool(value); err == nil {
+				t.allRoots = parsed
+				continue
+			}
+			t.rootMethods = make(map[string]bool)
+			for _, method := range strings.Split(value, "+") {
+				t.rootMethods[method] = true
+			}
+		case "name":
+			t.spanName = value
+		default:
+			return nil, fmt.Errorf("unknown option %s", key)
+		}
+	}
+	return t, nil
+}
+
+// startsRootSpan reports whether a method without a context.Context
+// parameter should still be traced.
+func (t tracer) startsRootSpan(method interpreter.DeclaredFunction) bool {
+	return t.allRoots || t.rootMethods[method.Name()]
+}
+
+func (t tracer) nameSpan(service *generator.ServiceModel, method interpreter.DeclaredFunction) string {
+	if name, ok := method.Directives().Value("name"); ok {
+		return name
+	}
+	if t.spanName == "" {
+		return method.Name()
+	}
+	return strings.NewReplacer(
+		"{interface}", service.TypeName,
+		"{method}", method.Name(),
+	).Replace(t.spanName)
 }
 
 func (t tracer) FileNamePrefix() string {
@@ -38,42 +91,46 @@ func (t tracer) ConfigureModel(model *generator.ServiceModel) {
 
 // GenerateFunctionImplementation generates a function
 // that depending on the method having a context.Context variable,
-// will either forward along, or create a new span from context
+// will either forward along, or create a new span from context.
+// Methods without one may start a root span instead, see the root option.
+// When the method returns an error, the span is tagged with it
+// before being finished.
 func (t tracer) GenerateFunctionImplementation(
 	builder *jen.Statement,
 	service *generator.ServiceModel,
 	method interpreter.DeclaredFunction,
 ) jen.Code {
-	ctxName := ""
-	hasContext := false
-	for _, variable := range method.Parameters() {
-		if typed, ok := variable.UnderlyingType().(*types.Named); ok {
-			obj := typed.Obj()
-			if obj.Name() == "Context" && obj.Pkg().Path() == "context" {
-				hasContext = true
-				ctxName = variable.Name()
-				break
-			}
-		}
-	}
+	ctxParam := method.ContextParameter()
+	spanName := method.LocalName("span")
+	traced := ctxParam != nil || t.startsRootSpan(method)
 	lines := make([]jen.Code, 0)
-	if hasContext {
+	if ctxParam != nil {
+		ctxName := ctxParam.Name()
 		startSpan := jen.List(
-			jen.Id("span"),
+			jen.Id(spanName),
 			jen.Id(ctxName),
 		).Op(":=").Id(service.StructPtr).Dot("tr").
 			Dot("StartSpanFromContext").
 			Call(
 				jen.Id(ctxName),
-				jen.Lit(method.Name()),
+				jen.Lit(t.nameSpan(service, method)),
 			)
 		/* code to generate
 		span, $ctxName := ${service.StructPtr}.tr.StartSpanFromContext($ctxName, ${DeclaredFunction.Name})
 		*/
 		lines = append(lines, startSpan)
-
+	} else if traced {
+		startSpan := jen.Id(spanName).Op(":=").Id(service.StructPtr).Dot("tr").
+			Dot("StartSpan").
+			Call(jen.Lit(t.nameSpan(service, method)))
+		/* code to generate
+		span := ${service.StructPtr}.tr.StartSpan(${DeclaredFunction.Name})
+		*/
+		lines = append(lines, startSpan)
+	}
+	if traced {
 		finisher := jen.Defer().Func().Call().Block(
-			jen.Id("span").Dot("Finish").Call(),
+			jen.Id(spanName).Dot("Finish").Call(),
 		).Call()
 		/* code to generate
 		defer func(){
@@ -82,15 +139,38 @@ func (t tracer) GenerateFunctionImplementation(
 		*/
 		lines = append(lines, jen.Line(), finisher, jen.Line())
 	}
-	var methodParams []jen.Code
-	for _, p := range method.Parameters() {
-		methodParams = append(methodParams, p.NamedParameter())
+	if !traced || !method.ReturnsError() {
+		returns := service.ForwardCall(method)
+		if len(method.Returns()) > 0 {
+			returns = jen.Return(returns)
+		}
+		/* code to generate
+		return ${service.StructPtr}.${service.ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+		*/
+		lines = append(lines, returns)
+		return builder.Block(lines...)
 	}
-	returns := jen.Return(jen.Id(service.StructPtr).Dot(service.ServicePtr).Dot(method.Name()).Params(methodParams...))
+
+	results := method.ResultNames()
+	resultIds := make([]jen.Code, len(results))
+	for i, r := range results {
+		resultIds[i] = jen.Id(r)
+	}
+	errName := results[len(results)-1]
 	/* code to generate
-	return ${service.StructPtr}.${service.ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+	r0, err := ${service.StructPtr}.${service.ServicePtr}.${DeclaredFunction.Name}(${DeclaredFunction.Parameters})
+	if err != nil {
+	  span.Tag("error", err.Error())
+	}
+	return r0, err
 	*/
-	lines = append(lines, returns)
+	lines = append(lines,
+		jen.List(resultIds...).Op(":=").Add(service.ForwardCall(method)),
+		jen.If(jen.Id(errName).Op("!=").Nil()).Block(
+			jen.Id(spanName).Dot("Tag").Call(jen.Lit("error"), jen.Id(errName).Dot("Error").Call()),
+		),
+		jen.Return(resultIds...),
+	)
 
 	return builder.Block(lines...)
 }